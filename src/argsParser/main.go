@@ -52,6 +52,7 @@ type arguments struct {
 	flagsOrder  []string
 	valuesOrder []string
 	required    map[string]bool
+	provided    map[string]bool
 	args        []string
 	help        map[string]string
 	colWidth    int
@@ -80,6 +81,7 @@ func New(args []string) arguments {
 		HasIssues:   false,
 		IsParsed:    false,
 		required:    make(map[string]bool),
+		provided:    make(map[string]bool),
 		Issues:      [][]string{},
 		help:        make(map[string]string),
 		colWidth:    1,
@@ -97,6 +99,8 @@ func New(args []string) arguments {
 }
 
 // AddFlag adds a boolean flag whose presence sets the flag to true.
+// It can also be given an explicit value (eg `-notify false`), which allows
+// a flag to be switched off.
 func (a *arguments) AddFlag(name string, required bool, defaultValue bool, help string) {
 	name = strings.ToLower(strings.TrimSpace(name))
 	a.Flags[name] = defaultValue
@@ -271,7 +275,11 @@ func (a *arguments) Parse() {
 				a.addIssue(a.Issues, i.Index, fmt.Sprintf("Unknown flag `-%s`", i.Tag))
 			}
 		} else if i.IsValue {
-			if _, found := a.Values[i.Tag]; !found {
+			if _, found := a.Flags[i.Tag]; found {
+				if _, err := strconv.ParseBool(i.Text); err != nil {
+					a.addIssue(a.Issues, i.Index, fmt.Sprintf("Flag `-%s` can only be true or false", i.Tag))
+				}
+			} else if _, found := a.Values[i.Tag]; !found {
 				a.addIssue(a.Issues, i.Index, fmt.Sprintf("Unknown item `-%s`", i.Tag))
 			}
 		} else {
@@ -286,6 +294,13 @@ func (a *arguments) Parse() {
 			if rf.IsFlag && rf.Tag == item {
 				isFound = true
 				a.Flags[item] = true
+				a.provided[item] = true
+			} else if rf.IsValue && rf.Tag == item {
+				if value, err := strconv.ParseBool(rf.Text); err == nil {
+					isFound = true
+					a.Flags[item] = value
+					a.provided[item] = true
+				}
 			}
 		}
 		if a.required[item] && !isFound {
@@ -298,6 +313,7 @@ func (a *arguments) Parse() {
 			if rf.IsValue && rf.Tag == item {
 				isFound = true
 				a.Values[item] = rf.Text
+				a.provided[item] = true
 			}
 		}
 		if a.required[item] && !isFound {
@@ -306,6 +322,12 @@ func (a *arguments) Parse() {
 	}
 }
 
+// IsProvided returns true if the flag or value was given on the command line,
+// as opposed to having its default.
+func (a *arguments) IsProvided(name string) bool {
+	return a.provided[strings.ToLower(strings.TrimSpace(name))]
+}
+
 // ShowIssues displays any argument issues in positional order.
 // It also details missing flags and values too.
 func (a *arguments) ShowIssues() {
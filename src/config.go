@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kcartlidge/ng/src/writer"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFilenames are the config files looked for in the `-folder`, in order.
+var ConfigFilenames = []string{"ng.yaml", "ng.yml", "ng.json"}
+
+// Config holds generation options which can be kept in version control
+// rather than repeated on the command line. Field names follow the flags.
+// Optional strings are pointers so an empty value can switch a feature off.
+type Config struct {
+	Env        string   `yaml:"env,omitempty" json:"env,omitempty"`
+	Schemas    []string `yaml:"schemas,omitempty" json:"schemas,omitempty"`
+	Module     string   `yaml:"module,omitempty" json:"module,omitempty"`
+	Repo       string   `yaml:"repo,omitempty" json:"repo,omitempty"`
+	Notify     *bool    `yaml:"notify,omitempty" json:"notify,omitempty"`
+	FromDump   string   `yaml:"from-dump,omitempty" json:"from-dump,omitempty"`
+	FromSQL    []string `yaml:"from-sql,omitempty" json:"from-sql,omitempty"`
+	Include    []string `yaml:"include,omitempty" json:"include,omitempty"`
+	Exclude    []string `yaml:"exclude,omitempty" json:"exclude,omitempty"`
+	SoftDelete *string  `yaml:"soft-delete,omitempty" json:"soft-delete,omitempty"`
+	CreatedAt  *string  `yaml:"created-at,omitempty" json:"created-at,omitempty"`
+	UpdatedAt  *string  `yaml:"updated-at,omitempty" json:"updated-at,omitempty"`
+
+	// Initialisms replace the defaults (eg `ID`, `URL`) if given.
+	// Table and column names map `[schema.]table[.column]` to a code name.
+	Initialisms []string          `yaml:"initialisms,omitempty" json:"initialisms,omitempty"`
+	TableNames  map[string]string `yaml:"table-names,omitempty" json:"table-names,omitempty"`
+	ColumnNames map[string]string `yaml:"column-names,omitempty" json:"column-names,omitempty"`
+
+	// Plurals are irregular singular to plural words, added to the defaults.
+	// Uncountable words are the same in both forms (eg `metadata`).
+	Plurals     map[string]string `yaml:"plurals,omitempty" json:"plurals,omitempty"`
+	Uncountable []string          `yaml:"uncountable,omitempty" json:"uncountable,omitempty"`
+
+	// Templates is a folder of templates overriding (or adding to) the embedded ones.
+	// Outputs are extra files to generate from templates (see `outputs.yaml`).
+	Templates *string         `yaml:"templates,omitempty" json:"templates,omitempty"`
+	Outputs   []writer.Output `yaml:"outputs,omitempty" json:"outputs,omitempty"`
+
+	// Filename is where the config was loaded from (if anywhere).
+	Filename string `yaml:"-" json:"-"`
+}
+
+// LoadConfig reads a YAML or JSON config file, based on its extension.
+func LoadConfig(filename string) (Config, error) {
+	config := Config{}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return config, err
+	}
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".json":
+		err = json.Unmarshal(data, &config)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &config)
+	default:
+		return config, fmt.Errorf("config %s should be .yaml, .yml, or .json", filename)
+	}
+	if err != nil {
+		return config, fmt.Errorf("config %s: %w", filename, err)
+	}
+	for _, o := range config.Outputs {
+		if err := o.Validate(); err != nil {
+			return config, fmt.Errorf("config %s: %w", filename, err)
+		}
+	}
+	config.resolvePaths(filepath.Dir(filename))
+	config.Filename = filename
+	return config, nil
+}
+
+// resolvePaths makes relative paths in the config relative to its folder
+// rather than to wherever Near Gothic happens to be run from.
+func (config *Config) resolvePaths(folder string) {
+	resolve := func(filename string) string {
+		if len(filename) == 0 || filepath.IsAbs(filename) {
+			return filename
+		}
+		return filepath.Join(folder, filename)
+	}
+	config.FromDump = resolve(config.FromDump)
+	for i := range config.FromSQL {
+		config.FromSQL[i] = resolve(config.FromSQL[i])
+	}
+	if config.Templates != nil {
+		templates := resolve(*config.Templates)
+		config.Templates = &templates
+	}
+}
+
+// FindConfig returns the first config file found in the folder.
+// If there isn't one the filename is empty (which is not an error).
+func FindConfig(folder string) (string, error) {
+	for _, name := range ConfigFilenames {
+		filename := filepath.Join(folder, name)
+		exists, err := Exists(filename)
+		if err != nil {
+			return "", err
+		}
+		if exists {
+			return filename, nil
+		}
+	}
+	return "", nil
+}
@@ -1,10 +1,12 @@
-module kcartlidge/ng
+module github.com/kcartlidge/ng/src
 
-go 1.18
+go 1.22.0
 
 require (
 	github.com/gertd/go-pluralize v0.2.1
 	github.com/jackc/pgx/v5 v5.6.0
+	golang.org/x/tools v0.26.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -12,6 +14,8 @@ require (
 	github.com/jackc/pgservicefile v0.0.0-20221227161230-091c0ba34f0a // indirect
 	github.com/jackc/puddle/v2 v2.2.1 // indirect
 	golang.org/x/crypto v0.17.0 // indirect
-	golang.org/x/sync v0.1.0 // indirect
+	golang.org/x/mod v0.21.0 // indirect
+	golang.org/x/sync v0.8.0 // indirect
+	golang.org/x/sys v0.26.0 // indirect
 	golang.org/x/text v0.14.0 // indirect
 )
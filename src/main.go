@@ -3,11 +3,17 @@ package main
 import (
 	"errors"
 	"fmt"
-	"kcartlidge/ng/argsParser"
 	"os"
 	"path"
+	"path/filepath"
 	"strings"
 	"time"
+
+	"github.com/kcartlidge/ng/src/argsParser"
+	"github.com/kcartlidge/ng/src/mapping"
+	"github.com/kcartlidge/ng/src/model"
+	"github.com/kcartlidge/ng/src/scanner"
+	"github.com/kcartlidge/ng/src/writer"
 )
 
 func main() {
@@ -23,12 +29,25 @@ func main() {
 	var a = argsParser.New(os.Args)
 	a.Example = "-w -env DB_CONNSTR -schema example -module kcartlidge/app -folder ~/Source/App -repo Data"
 	a.AddFlag("w", false, false, "overwrite any existing destination folder?")
+	a.AddFlag("notify", false, false, "add change notification triggers and payloads?")
+	a.AddFlag("check", false, false, "only check the existing code is up to date (for CI)?")
+	a.AddFlag("dry-run", false, false, "only show the files (and diffs) that would change?")
+	a.AddFlag("verify", false, false, "type-check the generated code within the parent module?")
 
 	a.AddValue("env", false, "DB_CONNSTR", "connection string environment variable")
-	a.AddValue("schema", false, "public", "the Postgres database schema to scan")
-	a.AddValue("folder", true, "", "the *parent* module's folder (eg `~/Source/App`)")
-	a.AddValue("module", true, "", "the *parent* Go module name (eg `kcartlidge/app`)")
-	a.AddValue("repo", true, "", "the short folder name for generated code (eg `Data`)")
+	a.AddValue("schema", false, "public", "the Postgres database schema(s) to scan, comma-separated")
+	a.AddValue("from-dump", false, "", "generate from a `dump.json` file instead of the database")
+	a.AddValue("from-sql", false, "", "generate from SQL DDL files/folders (comma-separated) instead")
+	a.AddValue("config", false, "", "an `ng.yaml` or `ng.json` file of options (optional)")
+	a.AddValue("folder", false, "", "the *parent* module's folder (eg `~/Source/App`)")
+	a.AddValue("module", false, "", "the *parent* Go module name (eg `kcartlidge/app`)")
+	a.AddValue("repo", false, "", "the short folder name for generated code (eg `Data`)")
+	a.AddValue("include", false, "", "only scan tables/views matching these globs, comma-separated")
+	a.AddValue("exclude", false, "", "skip tables/views matching these globs, comma-separated")
+	a.AddValue("soft-delete", false, "deleted_at", "nullable timestamp column used for soft deletes")
+	a.AddValue("created-at", false, "created_at", "timestamp column set automatically on insert")
+	a.AddValue("updated-at", false, "updated_at", "timestamp column set automatically on insert/update")
+	a.AddValue("templates", false, "", "a folder of `*.tmpl` files overriding/adding to the built-in ones")
 
 	a.AddNote("The `env` connection string should be suitable for `jackc/pgx`.")
 	a.AddNote("")
@@ -39,6 +58,35 @@ func main() {
 	a.AddNote("The new code will assume it is in a package named as `module`")
 	a.AddNote("plus `repo` (in lower case). For the example above, that means")
 	a.AddNote("`kcartlidge/app` + `Data` gives ``kcartlidge/app/data`.")
+	a.AddNote("")
+	a.AddNote("If several schemas are scanned (eg `-schema auth,billing`) then")
+	a.AddNote("generated names are prefixed by their schema (eg `AuthAccount`).")
+	a.AddNote("")
+	a.AddNote("Options can be kept in an `ng.yaml` (or `ng.yml`/`ng.json`) file in")
+	a.AddNote("the `folder`, or one given by `-config`. Any options also given")
+	a.AddNote("on the command line take precedence over those in the file, and")
+	a.AddNote("flags can be switched off with `false` (eg `-notify false`).")
+	a.AddNote("")
+	a.AddNote("With `-from-dump` no database connection is needed. The dump")
+	a.AddNote("already reflects any schema, filter, column, and naming options.")
+	a.AddNote("")
+	a.AddNote("With `-from-sql` the tables are read from `CREATE TABLE` etc")
+	a.AddNote("statements (eg migrations, or a generated `postgres.sql`).")
+	a.AddNote("")
+	a.AddNote("With `-check` nothing is written. Differences from the existing")
+	a.AddNote("code are listed, and the exit code is non-zero if there are any.")
+	a.AddNote("")
+	a.AddNote("With `-dry-run` nothing is written either. Files which would be")
+	a.AddNote("created, changed (with diffs), or removed are shown instead.")
+	a.AddNote("Only files generated previously (see `ng-manifest.json`) are")
+	a.AddNote("ever removed from the `repo` folder.")
+	a.AddNote("")
+	a.AddNote("With `-verify` the written code is type-checked as part of the")
+	a.AddNote("module in `folder` (which needs a `go.mod` and the Go toolchain).")
+	a.AddNote("")
+	a.AddNote("Any `-templates` file named as a built-in one (eg `repos.tmpl`)")
+	a.AddNote("replaces it. Extra files can be generated from others by adding")
+	a.AddNote("`outputs` to the config file.")
 
 	a.ShowUsage()
 	a.Parse()
@@ -47,51 +95,207 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Load any config file, either as given or found in the folder.
+	folder := a.Values["folder"]
+	configFile := a.Values["config"]
+	if len(configFile) == 0 && len(folder) > 0 {
+		found, err := FindConfig(folder)
+		check(err)
+		configFile = found
+	}
+	config := Config{}
+	if len(configFile) > 0 {
+		loaded, err := LoadConfig(configFile)
+		check(err)
+		config = loaded
+		if len(folder) == 0 {
+			folder = filepath.Dir(configFile)
+		}
+	}
+
+	// Command line values take precedence over the config file.
+	value := func(name string, configured *string) string {
+		if configured != nil && !a.IsProvided(name) {
+			return *configured
+		}
+		return a.Values[name]
+	}
+	list := func(name string, configured []string) []string {
+		if configured != nil && !a.IsProvided(name) {
+			return configured
+		}
+		return splitList(a.Values[name])
+	}
+	optional := func(configured string) *string {
+		if len(configured) == 0 {
+			return nil
+		}
+		return &configured
+	}
+
 	// Fetch and show config.
 	overwrite := a.Flags["w"]
-	env := a.Values["env"]
-	schema := a.Values["schema"]
-	parentModule := a.Values["module"]
-	folder := a.Values["folder"]
-	repoName := strings.ToLower(a.Values["repo"])
+	checkOnly := a.Flags["check"]
+	dryRun := a.Flags["dry-run"]
+	verify := a.Flags["verify"]
+	notify := a.Flags["notify"]
+	if config.Notify != nil && !a.IsProvided("notify") {
+		notify = *config.Notify
+	}
+	env := value("env", optional(config.Env))
+	fromDump := value("from-dump", optional(config.FromDump))
+	fromSQL := list("from-sql", config.FromSQL)
+	if len(fromDump) > 0 && len(fromSQL) > 0 {
+		check(errors.New("only one of -from-dump and -from-sql can be used"))
+	}
+	schemas := list("schema", config.Schemas)
+	filters := scanner.Filters{
+		Include: list("include", config.Include),
+		Exclude: list("exclude", config.Exclude),
+	}
+	if len(schemas) == 0 {
+		check(errors.New("no database schema was given"))
+	}
+	parentModule := value("module", optional(config.Module))
+	repoName := strings.ToLower(value("repo", optional(config.Repo)))
+	if len(folder) == 0 || len(parentModule) == 0 || len(repoName) == 0 {
+		check(errors.New("the folder, module, and repo are all required (by flag or config)"))
+	}
+	conventions := scanner.Conventions{
+		SoftDelete: value("soft-delete", config.SoftDelete),
+		CreatedAt:  value("created-at", config.CreatedAt),
+		UpdatedAt:  value("updated-at", config.UpdatedAt),
+	}
+	naming := scanner.Naming{
+		Tables:  config.TableNames,
+		Columns: config.ColumnNames,
+	}
+	namer := mapping.NewNamer(config.Initialisms, config.Plurals, config.Uncountable)
+	templatesFolder := value("templates", config.Templates)
+	if len(templatesFolder) > 0 {
+		exists, err := Exists(templatesFolder)
+		check(err)
+		if !exists {
+			check(fmt.Errorf("templates folder %s not found", templatesFolder))
+		}
+	}
 	module := path.Join(parentModule, repoName)
 	fmt.Println()
+	fmt.Println("Config file          :", configFile)
+	fmt.Println("From dump file       :", fromDump)
+	fmt.Println("From SQL files       :", strings.Join(fromSQL, ", "))
 	fmt.Println("Overwrite existing?  :", overwrite)
+	fmt.Println("Check only?          :", checkOnly)
+	fmt.Println("Dry run?             :", dryRun)
+	fmt.Println("Verify compiles?     :", verify)
+	fmt.Println("Notify on changes?   :", notify)
 	fmt.Println()
 	fmt.Println("Environment variable :", env)
-	fmt.Println("Database schema      :", schema)
+	fmt.Println("Database schema(s)   :", strings.Join(schemas, ", "))
+	fmt.Println("Include tables/views :", strings.Join(filters.Include, ", "))
+	fmt.Println("Exclude tables/views :", strings.Join(filters.Exclude, ", "))
 	fmt.Println("Go module name       :", module)
 	fmt.Println("Destination folder   :", folder)
 	fmt.Println("Repo package name    :", repoName)
+	fmt.Println("Soft delete column   :", conventions.SoftDelete)
+	fmt.Println("Created at column    :", conventions.CreatedAt)
+	fmt.Println("Updated at column    :", conventions.UpdatedAt)
+	fmt.Println("Name overrides       :", len(naming.Tables)+len(naming.Columns))
+	fmt.Println("Templates folder     :", templatesFolder)
+	fmt.Println("Extra outputs        :", len(config.Outputs))
 	fmt.Println()
 	fmt.Println()
 
-	// Fetch the connection string from the env, and test it.
-	connectionString, ok := os.LookupEnv(env)
-	if !ok {
-		check(errors.New("environment variable missing or unreadable"))
-	}
-	fmt.Println("Obtained connection string from environment")
+	// Create a schema model from a dump file, SQL files, or by scanning the database.
+	var schema model.Schema
+	if len(fromDump) > 0 {
+		loaded, err := scanner.LoadDump(fromDump, namer)
+		check(err)
+		schema = loaded
+		fmt.Println("Loaded schema from dump file")
+	} else {
+		options := scanner.Options{
+			SchemaNames: schemas,
+			Conventions: conventions,
+			Filters:     filters,
+			Naming:      naming,
+			Namer:       namer,
+			Log:         os.Stdout,
+		}
+		if len(fromSQL) == 0 {
+			// Fetch the connection string from the env, and test it.
+			connectionString, ok := os.LookupEnv(env)
+			if !ok {
+				check(errors.New("environment variable missing or unreadable"))
+			}
+			fmt.Println("Obtained connection string from environment")
+			options.ConnectionString = connectionString
+		}
 
-	// Scan the database to create a schema model.
-	s := NewScanner(connectionString, schema)
-	err := s.ScanPostgresDatabase()
-	check(err)
+		s, err := scanner.New(options)
+		check(err)
+		if len(fromSQL) > 0 {
+			schema, err = s.ScanSQLFiles(fromSQL)
+		} else {
+			schema, err = s.ScanPostgresDatabase()
+		}
+		check(err)
+	}
 
 	// Ensure there is something to write.
-	if len(s.Schema.Tables) == 0 {
+	if len(schema.Tables) == 0 {
 		check(errors.New("no tables were found."))
 	}
 
 	// Create the output.
 	fmt.Println()
-	w := NewWriter(folder, module, a.CommandLine, env, s.Schema, repoName)
-	exists, err := Exists(w.topFolder)
+	w, err := writer.New(schema, writer.Options{
+		Folder:                 folder,
+		RepoName:               repoName,
+		Module:                 module,
+		CommandLine:            a.CommandLine,
+		ConfigFile:             configFile,
+		ConnectionStringEnvArg: env,
+		Notify:                 notify,
+		TemplatesFolder:        templatesFolder,
+		Outputs:                config.Outputs,
+		Namer:                  namer,
+		Log:                    os.Stdout,
+	})
+	check(err)
+	if checkOnly {
+		isStale, err := w.Check()
+		check(err)
+		fmt.Println()
+		fmt.Printf("Done in %s\n", time.Since(started))
+		fmt.Println()
+		if isStale {
+			os.Exit(1)
+		}
+		return
+	}
+	if dryRun {
+		check(w.DryRun())
+		fmt.Println()
+		fmt.Printf("Done in %s\n", time.Since(started))
+		fmt.Println()
+		return
+	}
+	exists, err := Exists(folder)
 	check(err)
 	if exists && !overwrite {
 		check(errors.New("the output folder exists (-w overwrites)"))
 	}
-	w.WriteStuff()
+	check(w.Write())
+	if verify {
+		fmt.Println()
+		failed, err := w.Verify()
+		check(err)
+		if failed {
+			fmt.Println()
+			check(errors.New("the generated code does not compile"))
+		}
+	}
 
 	// Advisory.
 	fmt.Println()
@@ -117,6 +321,17 @@ func check(err error) {
 	}
 }
 
+// splitList returns the non-empty, trimmed, entries in a comma-separated list.
+func splitList(list string) []string {
+	result := []string{}
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); len(item) > 0 {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
 // Exists ... Returns true if the path/filename can be found.
 func Exists(filename string) (bool, error) {
 	_, err := os.Stat(filename)
@@ -0,0 +1,438 @@
+// Package mapping derives code names and Go types from Postgres ones, and
+// provides the helpers used by the templates to build SQL statements.
+package mapping
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	pluralize "github.com/gertd/go-pluralize"
+	"github.com/kcartlidge/ng/src/model"
+)
+
+// PostgresTypeToGo returns the Go type used for a Postgres data type.
+func PostgresTypeToGo(postgresDataType string) (string, error) {
+	switch strings.ToLower(postgresDataType) {
+	case "smallint", "smallserial":
+		return "int16", nil
+	case "integer", "serial":
+		return "int", nil
+	case "bigint", "bigserial":
+		return "int64", nil
+	case "decimal", "numeric", "money":
+		return "float64", nil
+	case "real":
+		return "float64", nil
+	case "double precision":
+		return "float64", nil
+	case "bytea":
+		return "[]byte", nil
+	case "character varying", "varchar", "character", "char", "text":
+		return "string", nil
+	case "boolean":
+		return "bool", nil
+	case "bit":
+		return "", errors.New("unsupported column type 'bit' - use 'boolean' instead")
+	case "timestamp", "timestamptz", "timestamp with time zone", "timestamp without time zone", "date", "time", "time with time zone", "time without time zone":
+		return "*time.Time", nil
+	case "interval":
+		return "*time.Duration", nil
+	case "uuid":
+		return "Guid", nil
+	case "json", "jsonb":
+		return "string", nil
+	case "xml":
+		return "string", nil
+	}
+	return "", fmt.Errorf("unsupported Postgres data type: %s", postgresDataType)
+}
+
+// IsPostgresTypeCardinal returns true for the integer types.
+func IsPostgresTypeCardinal(postgresDataType string) bool {
+	switch strings.ToLower(postgresDataType) {
+	case "smallint", "smallserial", "integer", "serial", "bigint", "bigserial":
+		return true
+	}
+	return false
+}
+
+// PostgresTypeToGoWithNullable returns the Go type for a column, which is
+// a pointer if the column is nullable.
+func PostgresTypeToGoWithNullable(postgresDataType string, isNullable bool) (string, error) {
+	goType, err := PostgresTypeToGo(postgresDataType)
+	if err != nil {
+		return "", err
+	}
+	if isNullable && !strings.HasPrefix(goType, "*") {
+		return "*" + goType, nil
+	}
+	return goType, nil
+}
+
+// defaultInitialisms are golint's common initialisms.
+var defaultInitialisms = []string{
+	"ACL", "API", "ASCII", "CPU", "CSS", "DNS", "EOF", "GUID", "HTML", "HTTP",
+	"HTTPS", "ID", "IP", "JSON", "LHS", "QPS", "RAM", "RHS", "RPC", "SLA",
+	"SMTP", "SQL", "SSH", "TCP", "TLS", "TTL", "UDP", "UI", "UID", "UUID",
+	"URI", "URL", "UTF8", "VM", "XML", "XMPP", "XSRF", "XSS",
+}
+
+// Namer derives code and display names, with its own initialisms (words shown
+// in upper case within code names, eg `ID`, `URL`) and pluralisation rules.
+type Namer struct {
+	initialisms map[string]bool
+	plural      *pluralize.Client
+}
+
+// NewNamer creates a namer. Nil initialisms means golint's common ones, and
+// an empty list means words are never fully upper-cased. Irregular (singular
+// to plural) and uncountable words are added to the default pluralisation
+// rules, for domain nouns which they get wrong.
+func NewNamer(initialisms []string, irregular map[string]string, uncountable []string) *Namer {
+	if initialisms == nil {
+		initialisms = defaultInitialisms
+	}
+	n := &Namer{initialisms: toInitialisms(initialisms), plural: pluralize.NewClient()}
+	for single, plurals := range irregular {
+		n.plural.AddIrregularRule(single, plurals)
+	}
+	for _, word := range uncountable {
+		if word = strings.TrimSpace(word); len(word) > 0 {
+			n.plural.AddUncountableRule(word)
+		}
+	}
+	return n
+}
+
+func toInitialisms(words []string) map[string]bool {
+	result := make(map[string]bool)
+	for _, word := range words {
+		if word = strings.TrimSpace(word); len(word) > 0 {
+			result[strings.ToLower(word)] = true
+		}
+	}
+	return result
+}
+
+// ToJsonName returns the value in camel case (eg `userId`).
+func ToJsonName(value string) string {
+	if len(value) == 0 {
+		return ""
+	}
+	s := toProperCase(value, false, nil)
+	return strings.ToLower(s)[:1] + s[1:]
+}
+
+// ToSlug returns the value in lower case with hyphens (eg `user-id`).
+func ToSlug(value string) string {
+	if len(value) == 0 {
+		return ""
+	}
+	s := strings.ToLower(toProperCase(value, true, nil))
+	return strings.ReplaceAll(s, " ", "-")
+}
+
+// ToProper returns the value in proper case, with words separated by spaces
+// if it is for display. Code names upper-case any initialisms (eg `APIKey`).
+func (n *Namer) ToProper(value string, forDisplay bool) string {
+	if forDisplay {
+		return toProperCase(value, true, nil)
+	}
+	return toProperCase(value, false, n.initialisms)
+}
+
+func toProperCase(value string, forDisplay bool, upper map[string]bool) string {
+	if len(value) == 0 {
+		return ""
+	}
+	words := []string{}
+	for _, word := range strings.Split(strings.ToLower(value), "_") {
+		if len(word) == 0 {
+			continue
+		}
+		if upper[word] {
+			words = append(words, strings.ToUpper(word))
+		} else {
+			runes := []rune(word)
+			words = append(words, strings.ToUpper(string(runes[0]))+string(runes[1:]))
+		}
+	}
+	separator := ""
+	if forDisplay {
+		separator = " "
+	}
+	return strings.Join(words, separator)
+}
+
+// TakeDirective looks for an `ng:<name>` directive in a comment.
+// It returns the comment with any such directive removed, and whether it was found.
+func TakeDirective(comment string, name string) (string, bool) {
+	directive := "ng:" + name
+	found := false
+	words := []string{}
+	for _, word := range strings.Fields(comment) {
+		if strings.ToLower(word) == directive {
+			found = true
+		} else {
+			words = append(words, word)
+		}
+	}
+	if !found {
+		return comment, false
+	}
+	return strings.Join(words, " "), true
+}
+
+// MarkVersionColumn ensures a table has at most one optimistic concurrency column.
+// A column whose comment has an `ng:version` directive is used if present, otherwise
+// a cardinal column named `version` or `row_version` is chosen by convention.
+// Version columns must not be nullable or primary keys, and must be numeric or timestamps.
+func MarkVersionColumn(table *model.Table) {
+	found := false
+	for i := range table.Columns {
+		col := &table.Columns[i]
+		if col.IsVersion {
+			col.IsVersion = !found && isVersionColumnType(*col)
+			found = found || col.IsVersion
+		}
+	}
+	if found || !table.IsUpdatable {
+		return
+	}
+	for i := range table.Columns {
+		col := &table.Columns[i]
+		switch strings.ToLower(col.ColumnName) {
+		case "version", "row_version":
+			if col.IsCardinal && isVersionColumnType(*col) {
+				col.IsVersion = true
+				return
+			}
+		}
+	}
+}
+
+// MarkSoftDeleteColumn flags the named column as recording when a row was soft-deleted.
+// Only nullable timestamp columns in updatable tables qualify.
+func MarkSoftDeleteColumn(table *model.Table, columnName string) {
+	if len(columnName) == 0 || !table.IsUpdatable {
+		return
+	}
+	for i := range table.Columns {
+		col := &table.Columns[i]
+		if strings.EqualFold(col.ColumnName, columnName) {
+			col.IsSoftDelete = col.IsNullable && !col.IsPrimaryKey && col.DataType == "*time.Time"
+			return
+		}
+	}
+}
+
+// MarkAuditColumns flags the named columns as recording when a row was created and last updated.
+// Only timestamp columns in updatable tables qualify.
+func MarkAuditColumns(table *model.Table, createdAt string, updatedAt string) {
+	if !table.IsUpdatable {
+		return
+	}
+	for i := range table.Columns {
+		col := &table.Columns[i]
+		if col.IsPrimaryKey || col.DataType != "*time.Time" {
+			continue
+		}
+		col.IsCreatedAt = len(createdAt) > 0 && strings.EqualFold(col.ColumnName, createdAt)
+		col.IsUpdatedAt = len(updatedAt) > 0 && strings.EqualFold(col.ColumnName, updatedAt)
+	}
+}
+
+// isVersionColumnType returns true if the column can be used for optimistic concurrency.
+func isVersionColumnType(col model.Column) bool {
+	if col.IsPrimaryKey || col.IsNullable {
+		return false
+	}
+	return col.IsCardinal || col.DataType == "*time.Time"
+}
+
+// QuoteIdentifier returns a double-quoted SQL identifier.
+func QuoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// ToPlural returns a pluralised version of the given text.
+// Only the last word is changed, whether the text is in proper case (`AccountSetting`),
+// or separated by spaces, hyphens, or underscores (`Account Setting`, `account-setting`).
+// A trailing initialism just gains an `s` (`UserIDs`).
+func (n *Namer) ToPlural(value string) string {
+	runes := []rune(value)
+	start := len(runes)
+	for start > 0 && !strings.ContainsRune(" -_", runes[start-1]) {
+		start--
+	}
+	if start == len(runes) {
+		return value
+	}
+	if unicode.IsUpper(runes[len(runes)-1]) {
+		return value + "s"
+	}
+	for i := len(runes) - 1; i > start; i-- {
+		if unicode.IsUpper(runes[i]) {
+			start = i
+			break
+		}
+	}
+	return string(runes[:start]) + n.plural.Plural(string(runes[start:]))
+}
+
+// ToColumnNameListCSV returns the database column names comma-delimited
+func ToColumnNameListCSV(table model.Table) string {
+	s := ""
+	for _, col := range table.Columns {
+		if len(s) > 0 {
+			s += ","
+		}
+		s += QuoteIdentifier(col.ColumnName)
+	}
+	return s
+}
+
+// ToColumnNameListNoPrimaryKeysCSV returns the database column names comma-delimited
+// Primary keys are omitted
+func ToColumnNameListNoPrimaryKeysCSV(table model.Table) string {
+	s := ""
+	for _, col := range table.Columns {
+		if col.IsPrimaryKey {
+			continue
+		}
+		if len(s) > 0 {
+			s += ","
+		}
+		s += QuoteIdentifier(col.ColumnName)
+	}
+	return s
+}
+
+// ToPrimaryKeyParametersCSV returns the primary key fields as comma-delimited parameters
+func ToPrimaryKeyParametersCSV(table model.Table) string {
+	s := ""
+	for _, col := range table.Columns {
+		if col.IsPrimaryKey {
+			if len(s) > 0 {
+				s += ","
+			}
+			s += fmt.Sprintf("%s %s", col.JsonName, col.DataType)
+		}
+	}
+	return s
+}
+
+// ToPrimaryKeyArgumentsCSV returns the primary key parameter names comma-delimited
+// They match the names used by ToPrimaryKeyParametersCSV
+func ToPrimaryKeyArgumentsCSV(table model.Table) string {
+	s := ""
+	for _, col := range table.Columns {
+		if col.IsPrimaryKey {
+			if len(s) > 0 {
+				s += ","
+			}
+			s += col.JsonName
+		}
+	}
+	return s
+}
+
+// ToCodeNameListCSV returns the code column names comma-delimited
+// The prefix allows the columns to be 'attached' to something
+func ToCodeNameListCSV(table model.Table, prefix string) string {
+	s := ""
+	for _, col := range table.Columns {
+		if len(s) > 0 {
+			s += ","
+		}
+		s += (prefix + col.CodeName)
+	}
+	return s
+}
+
+// ToParameterListNoPrimaryKeysCSV returns comma-delimited '$n' parameters for SQL insert statements.
+// Primary keys are omitted.
+func ToParameterListNoPrimaryKeysCSV(table model.Table) string {
+	s := ""
+	i := 0
+	for _, col := range table.Columns {
+		if col.IsPrimaryKey {
+			continue
+		}
+		if len(s) > 0 {
+			s += ","
+		}
+		if !col.IsInsertParameter() {
+			s += "NOW()"
+			continue
+		}
+		i++
+		s += fmt.Sprintf("$%v", i)
+	}
+	return s
+}
+
+// ToUpdateListNoPrimaryKeysCSV returns comma-delimited field='$n' parameters for SQL update statements.
+// Primary keys and created timestamps are omitted.
+// Any version column is incremented (or set to the current time) rather than parameterised,
+// as is any updated timestamp.
+func ToUpdateListNoPrimaryKeysCSV(table model.Table) string {
+	s := ""
+	i := 0
+	for _, col := range table.Columns {
+		if col.IsPrimaryKey || (col.IsCreatedAt && !col.IsVersion) {
+			continue
+		}
+		if len(s) > 0 {
+			s += ","
+		}
+		if col.IsUpdateParameter() {
+			i++
+			s += fmt.Sprintf("%s=$%v", QuoteIdentifier(col.ColumnName), i)
+		} else {
+			s += toAutomaticUpdate(col)
+		}
+	}
+	return s
+}
+
+// ToAutomaticUpdates returns the field=value assignments for SQL update statements
+// that don't come from the entity (version columns and updated timestamps).
+func ToAutomaticUpdates(table model.Table) []string {
+	result := []string{}
+	for _, col := range table.Columns {
+		if a := toAutomaticUpdate(col); len(a) > 0 {
+			result = append(result, a)
+		}
+	}
+	return result
+}
+
+// toAutomaticUpdate returns the field=value assignment for a column maintained
+// automatically by update statements, or an empty string if it isn't.
+func toAutomaticUpdate(col model.Column) string {
+	switch {
+	case col.IsPrimaryKey:
+		return ""
+	case col.IsVersion && col.IsCardinal:
+		name := QuoteIdentifier(col.ColumnName)
+		return fmt.Sprintf("%s=%s+1", name, name)
+	case col.IsVersion, col.IsUpdatedAt:
+		return fmt.Sprintf("%s=NOW()", QuoteIdentifier(col.ColumnName))
+	}
+	return ""
+}
+
+// ColumnIdxAfterPrimaryKeys returns the parameter number following the updated columns.
+// Only columns whose values come from the entity are counted.
+func ColumnIdxAfterPrimaryKeys(table model.Table) int {
+	c := 0
+	for _, col := range table.Columns {
+		if col.IsUpdateParameter() {
+			c++
+		}
+	}
+	return c + 1
+}
@@ -1,4 +1,4 @@
-package main
+package model
 
 import (
 	"fmt"
@@ -73,7 +73,11 @@ func (t *Table) getConstraintSQL() string {
 		columnNames := strings.Join(c.ColumnNames, ", ")
 		txt += fmt.Sprintf(",\n    CONSTRAINT %s %s (%s)", c.ConstraintName, c.ConstraintType, columnNames)
 		if c.IsForeignKey {
-			txt += fmt.Sprintf("\n        REFERENCES %s.%s (%s) MATCH SIMPLE ", t.SchemaName, *c.ForeignTable, *c.ForeignColumn)
+			foreignSchema := t.SchemaName
+			if c.ForeignSchema != nil {
+				foreignSchema = *c.ForeignSchema
+			}
+			txt += fmt.Sprintf("\n        REFERENCES %s.%s (%s) MATCH SIMPLE ", foreignSchema, *c.ForeignTable, *c.ForeignColumn)
 			txt += "\n        ON UPDATE NO ACTION ON DELETE NO ACTION"
 		}
 	}
@@ -94,3 +98,39 @@ func (t *Table) getCommentsSQL() string {
 	}
 	return txt
 }
+
+// GetNotifySQL returns a trigger which sends a notification for each inserted, updated, or
+// deleted row. The JSON payload has the operation and the primary key(s).
+func (t Table) GetNotifySQL() string {
+	if t.TableType != "BASE TABLE" {
+		return ""
+	}
+	keys := func(record string) string {
+		s := "'operation', TG_OP"
+		for _, c := range t.Columns {
+			if c.IsPrimaryKey {
+				s += fmt.Sprintf(", '%s', %s.%s", c.JsonName, record, c.ColumnName)
+			}
+		}
+		return s
+	}
+	functionName := fmt.Sprintf("%s.%s_notify", t.SchemaName, t.TableName)
+	channel := strings.ReplaceAll(t.NotifyChannel(), "'", "''")
+
+	txt := "\n"
+	txt += fmt.Sprintf("CREATE OR REPLACE FUNCTION %s() RETURNS trigger AS $$\n", functionName)
+	txt += "BEGIN\n"
+	txt += "    IF TG_OP = 'DELETE' THEN\n"
+	txt += fmt.Sprintf("        PERFORM pg_notify('%s', json_build_object(%s)::text);\n", channel, keys("OLD"))
+	txt += "        RETURN OLD;\n"
+	txt += "    END IF;\n"
+	txt += fmt.Sprintf("    PERFORM pg_notify('%s', json_build_object(%s)::text);\n", channel, keys("NEW"))
+	txt += "    RETURN NEW;\n"
+	txt += "END;\n"
+	txt += "$$ LANGUAGE plpgsql;\n"
+	txt += "\n"
+	txt += fmt.Sprintf("DROP TRIGGER IF EXISTS %s_notify ON %s.%s;\n", t.TableName, t.SchemaName, t.TableName)
+	txt += fmt.Sprintf("CREATE TRIGGER %s_notify AFTER INSERT OR UPDATE OR DELETE ON %s.%s\n", t.TableName, t.SchemaName, t.TableName)
+	txt += fmt.Sprintf("    FOR EACH ROW EXECUTE FUNCTION %s();\n", functionName)
+	return txt
+}
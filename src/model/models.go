@@ -0,0 +1,155 @@
+// Package model describes a scanned database schema, from which code is generated.
+// It can be adjusted (eg renaming or dropping tables) before being written.
+package model
+
+import (
+	"encoding/json"
+)
+
+// Schema is the database schema (or schemas) which code is generated for.
+type Schema struct {
+	SchemaName  string   `json:"schemaName"`
+	SchemaNames []string `json:"schemaNames"`
+	CodeName    string   `json:"codeName"`
+	DisplayName string   `json:"displayName"`
+	JsonName    string   `json:"jsonName"`
+	SlugName    string   `json:"slugName"`
+
+	Owner   string         `json:"owner"`
+	Tables  []Table        `json:"tables"`
+	Skipped []SkippedTable `json:"skipped"`
+}
+
+// SkippedTable records a table or view excluded from generation, and why.
+type SkippedTable struct {
+	SchemaName string `json:"schemaName"`
+	TableName  string `json:"tableName"`
+	TableType  string `json:"tableType"`
+	Reason     string `json:"reason"`
+}
+
+// Table is a table or view within the schema.
+type Table struct {
+	SchemaName        string `json:"schemaName"`
+	TableName         string `json:"tableName"`
+	CodeName          string `json:"codeName"`
+	CodeNamePlural    string `json:"codeNamePlural"`
+	DisplayName       string `json:"displayName"`
+	DisplayNamePlural string `json:"displayNamePlural"`
+	JsonName          string `json:"jsonName"`
+	JsonNamePlural    string `json:"jsonNamePlural"`
+	SlugName          string `json:"slugName"`
+	SlugNamePlural    string `json:"slugNamePlural"`
+
+	Owner       string       `json:"owner"`
+	Comment     string       `json:"comment"`
+	TableType   string       `json:"tableType"`
+	IsUpdatable bool         `json:"isUpdatable"`
+	Columns     []Column     `json:"columns"`
+	Constraints []Constraint `json:"constraints"`
+	Indexes     []Index      `json:"indexes"`
+
+	CodeImports []string `json:"codeImports"`
+}
+
+// Column is a column within a table or view.
+type Column struct {
+	Position    int    `json:"position"`
+	ColumnName  string `json:"columnName"`
+	CodeName    string `json:"codeName"`
+	DisplayName string `json:"displayName"`
+	JsonName    string `json:"jsonName"`
+	SlugName    string `json:"slugName"`
+
+	Comment          string  `json:"comment"`
+	IsPrimaryKey     bool    `json:"isPrimaryKey"`
+	IsNullable       bool    `json:"isNullable"`
+	IsCardinal       bool    `json:"isCardinal"`
+	HasMaxLen        bool    `json:"hasMaxLen"`
+	HasDefault       bool    `json:"hasDefault"`
+	HasPrecision     bool    `json:"hasPrecision"`
+	CanFilter        bool    `json:"canFilter"`
+	IsVersion        bool    `json:"isVersion"`
+	IsSoftDelete     bool    `json:"isSoftDelete"`
+	IsCreatedAt      bool    `json:"isCreatedAt"`
+	IsUpdatedAt      bool    `json:"isUpdatedAt"`
+	SqlType          string  `json:"sqlType"`
+	DataType         string  `json:"dataType"`
+	MaxLen           *int    `json:"maxLen,omitempty"`
+	ColumnDefault    *string `json:"columnDefault,omitempty"`
+	NumericPrecision *int    `json:"numericPrecision,omitempty"`
+}
+
+// Constraint is a primary, foreign, or unique key.
+type Constraint struct {
+	ConstraintName string `json:"constraintName"`
+	CodeName       string `json:"codeName"`
+	DisplayName    string `json:"displayName"`
+	JsonName       string `json:"jsonName"`
+	SlugName       string `json:"slugName"`
+
+	IsPrimaryKey    bool     `json:"isPrimaryKey"`
+	IsForeignKey    bool     `json:"isForeignKey"`
+	IsUniqueKey     bool     `json:"isUniqueKey"`
+	ColumnNames     []string `json:"columnNames"`
+	ConstraintType  string   `json:"constraintType"`
+	ForeignSchema   *string  `json:"foreignSchema,omitempty"`
+	ForeignTable    *string  `json:"foreignTable,omitempty"`
+	ForeignColumn   *string  `json:"foreignColumn,omitempty"`
+	ForeignCodeName *string  `json:"foreignCodeName,omitempty"`
+}
+
+// Index is an index on (plain) columns.
+type Index struct {
+	IndexName   string `json:"indexName"`
+	CodeName    string `json:"codeName"`
+	DisplayName string `json:"displayName"`
+	JsonName    string `json:"jsonName"`
+	SlugName    string `json:"slugName"`
+
+	ColumnNames  []string `json:"columnNames"`
+	IsPrimaryKey bool     `json:"isPrimaryKey"`
+	IsUnique     bool     `json:"isUnique"`
+}
+
+// VersionColumn returns the column used for optimistic concurrency, or nil if there isn't one.
+func (t Table) VersionColumn() *Column {
+	for i := range t.Columns {
+		if t.Columns[i].IsVersion {
+			return &t.Columns[i]
+		}
+	}
+	return nil
+}
+
+// SoftDeleteColumn returns the column marking rows as soft-deleted, or nil if there isn't one.
+func (t Table) SoftDeleteColumn() *Column {
+	for i := range t.Columns {
+		if t.Columns[i].IsSoftDelete {
+			return &t.Columns[i]
+		}
+	}
+	return nil
+}
+
+// NotifyChannel returns the channel used for the table's change notifications.
+func (t Table) NotifyChannel() string {
+	return t.SchemaName + "_" + t.TableName
+}
+
+// IsInsertParameter returns true if Insert takes the column's value from the entity.
+// Primary keys are assigned by the database, and audit timestamps are set automatically.
+func (c Column) IsInsertParameter() bool {
+	return !c.IsPrimaryKey && !c.IsCreatedAt && !c.IsUpdatedAt
+}
+
+// IsUpdateParameter returns true if Update takes the column's value from the entity.
+// Version columns and audit timestamps are maintained automatically.
+func (c Column) IsUpdateParameter() bool {
+	return !c.IsPrimaryKey && !c.IsVersion && !c.IsCreatedAt && !c.IsUpdatedAt
+}
+
+// ToJSON returns the schema as indented JSON, as written to `dump.json`.
+func (schema Schema) ToJSON() ([]byte, error) {
+	return json.MarshalIndent(schema, "", "\t")
+}
@@ -0,0 +1,1464 @@
+package scanner
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kcartlidge/ng/src/mapping"
+	"github.com/kcartlidge/ng/src/model"
+)
+
+// SQL token kinds.
+const (
+	sqlWord   = iota // keywords, unquoted identifiers, and numbers
+	sqlQuoted        // double-quoted identifiers
+	sqlString        // string literals (including dollar-quoted ones)
+	sqlSymbol        // punctuation and operators
+)
+
+type sqlToken struct {
+	kind int
+	text string
+}
+
+// ddlTable is a table as declared by the DDL statements so far.
+// Comments may come after the table, so the model is only built at the end.
+type ddlTable struct {
+	schemaName   string
+	tableName    string
+	comment      string
+	columns      []ddlColumn
+	constraints  []model.Constraint
+	indexes      []model.Index
+	lastPosition int
+}
+
+// ddlColumn is a column as declared so far. Positions are not reused when
+// columns are dropped, as with `information_schema.columns`.
+type ddlColumn struct {
+	position         int
+	name             string
+	dataType         string
+	isNullable       bool
+	maxLen           *int
+	numericPrecision *int
+	columnDefault    *string
+	comment          string
+}
+
+// ddlParser reads `CREATE TABLE`, `CREATE INDEX`, `ALTER TABLE`, `COMMENT ON`,
+// and `DROP TABLE` statements. Anything else (functions, triggers, views etc)
+// is ignored. Unqualified names are in the default schema, which is the
+// first one scanned unless changed with a `SET search_path`.
+//
+// Views are recorded so that statements about them (eg `ALTER TABLE ... OWNER TO`)
+// can be skipped quietly. Those for other relations not created by the SQL are
+// skipped with a warning.
+type ddlParser struct {
+	tables        []*ddlTable
+	views         map[string]bool
+	defaultSchema string
+	tokens        []sqlToken
+	pos           int
+	statement     string
+	namer         *mapping.Namer
+	log           io.Writer
+}
+
+// ScanSQLFiles creates a schema model from SQL DDL files instead of a database.
+// Folders are expanded to the `.sql` files within them, in name order.
+func (s *Scanner) ScanSQLFiles(filenames []string) (model.Schema, error) {
+	files, err := expandSQLFiles(filenames)
+	if err != nil {
+		return model.Schema{}, err
+	}
+	p := ddlParser{tables: []*ddlTable{}, views: map[string]bool{}, defaultSchema: s.SchemaNames[0], namer: s.Namer, log: s.log}
+	for _, filename := range files {
+		fmt.Fprintf(s.log, "Reading `%s`\n", filename)
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			return model.Schema{}, err
+		}
+		if err := p.parse(string(data)); err != nil {
+			return model.Schema{}, fmt.Errorf("%s: %w", filename, err)
+		}
+	}
+
+	// Tables are added by schema then name, as when scanning a database.
+	s.Schema = s.newSchema()
+	for _, schemaName := range s.SchemaNames {
+		tables := []*ddlTable{}
+		for _, t := range p.tables {
+			if t.schemaName == schemaName {
+				tables = append(tables, t)
+			}
+		}
+		sort.Slice(tables, func(i, j int) bool { return tables[i].tableName < tables[j].tableName })
+		for _, t := range tables {
+			if !s.isIncluded(t.schemaName, t.tableName, "BASE TABLE") {
+				continue
+			}
+			fmt.Fprintf(s.log, "Reading table `%s.%s`\n", t.schemaName, t.tableName)
+			table, err := s.newTableFromDDL(t, p)
+			if err != nil {
+				return model.Schema{}, err
+			}
+			s.addTable(table)
+		}
+	}
+	s.resolveForeignKeys()
+	return s.Schema, nil
+}
+
+// expandSQLFiles replaces any folders with the `.sql` files they contain.
+func expandSQLFiles(filenames []string) ([]string, error) {
+	result := []string{}
+	for _, filename := range filenames {
+		info, err := os.Stat(filename)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			result = append(result, filename)
+			continue
+		}
+		matches, err := filepath.Glob(filepath.Join(filename, "*.sql"))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(matches)
+		result = append(result, matches...)
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("no SQL files found in %s", strings.Join(filenames, ", "))
+	}
+	return result, nil
+}
+
+// newTableFromDDL builds the model for a declared table.
+func (s *Scanner) newTableFromDDL(t *ddlTable, p ddlParser) (model.Table, error) {
+	table := s.newTable(t.schemaName, t.tableName, "BASE TABLE", true, t.comment)
+	for _, c := range t.columns {
+		col, err := s.newColumn(t.schemaName, t.tableName, false, c.position, c.name, c.dataType, c.isNullable, c.comment)
+		if err != nil {
+			return table, err
+		}
+		col.HasMaxLen, col.MaxLen = c.maxLen != nil, c.maxLen
+		col.HasDefault, col.ColumnDefault = c.columnDefault != nil, c.columnDefault
+		col.HasPrecision, col.NumericPrecision = c.numericPrecision != nil, c.numericPrecision
+		table.Columns = append(table.Columns, col)
+	}
+	for _, c := range t.constraints {
+		if c.IsForeignKey && c.ForeignColumn == nil {
+			// A reference without columns is to the primary key.
+			target := p.find(*c.ForeignSchema, *c.ForeignTable)
+			if target == nil || target.primaryKey() == nil {
+				return table, fmt.Errorf("foreign key `%s` on `%s.%s` references `%s.%s` without a known primary key",
+					c.ConstraintName, t.schemaName, t.tableName, *c.ForeignSchema, *c.ForeignTable)
+			}
+			c.ForeignColumn = &target.primaryKey().ColumnNames[0]
+		}
+		table.Constraints = append(table.Constraints, c)
+	}
+	for _, idx := range t.indexes {
+		markIndexedColumn(&table, idx)
+		table.Indexes = append(table.Indexes, idx)
+	}
+	return table, nil
+}
+
+// primaryKey returns the table's primary key constraint, if any.
+func (t *ddlTable) primaryKey() *model.Constraint {
+	for i := range t.constraints {
+		if t.constraints[i].IsPrimaryKey {
+			return &t.constraints[i]
+		}
+	}
+	return nil
+}
+
+// column returns the named column, if any.
+func (t *ddlTable) column(name string) *ddlColumn {
+	for i := range t.columns {
+		if t.columns[i].name == name {
+			return &t.columns[i]
+		}
+	}
+	return nil
+}
+
+// addConstraint records a constraint, along with the index Postgres creates
+// for primary and unique keys.
+func (p *ddlParser) addConstraint(t *ddlTable, c model.Constraint) {
+	t.constraints = append(t.constraints, c)
+	if c.IsPrimaryKey || c.IsUniqueKey {
+		t.indexes = append(t.indexes, newIndex(p.namer, c.ConstraintName, c.IsPrimaryKey, true, c.ColumnNames))
+	}
+	if c.IsPrimaryKey {
+		for _, name := range c.ColumnNames {
+			if col := t.column(name); col != nil {
+				col.isNullable = false
+			}
+		}
+	}
+}
+
+// relation returns the named table that a statement applies to. If there isn't
+// one then nil is returned, and unless it's a view (or the statement said
+// `IF EXISTS`) a warning is shown as the statement is skipped.
+func (p *ddlParser) relation(schemaName string, tableName string, ifExists bool) *ddlTable {
+	if t := p.find(schemaName, tableName); t != nil {
+		return t
+	}
+	if !ifExists && !p.views[schemaName+"."+tableName] {
+		fmt.Fprintf(p.log, "Skipping `%s` as `%s.%s` is not a table created by the SQL\n", p.statement, schemaName, tableName)
+	}
+	return nil
+}
+
+// hasIndex returns true if the schema has an index of that name, including
+// those for primary and unique keys.
+func (p *ddlParser) hasIndex(schemaName string, indexName string) bool {
+	for _, t := range p.tables {
+		for _, idx := range t.indexes {
+			if t.schemaName == schemaName && idx.IndexName == indexName {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// find returns the named table, if it has been declared.
+func (p *ddlParser) find(schemaName string, tableName string) *ddlTable {
+	for _, t := range p.tables {
+		if t.schemaName == schemaName && t.tableName == tableName {
+			return t
+		}
+	}
+	return nil
+}
+
+// parse reads the statements in the SQL text.
+func (p *ddlParser) parse(text string) error {
+	tokens, err := tokenizeSQL(text)
+	if err != nil {
+		return err
+	}
+	start := 0
+	for i := 0; i <= len(tokens); i++ {
+		if i < len(tokens) && !tokens[i].is(";") {
+			continue
+		}
+		if i > start {
+			p.tokens, p.pos = tokens[start:i], 0
+			p.statement = summariseTokens(p.tokens)
+			if err := p.parseStatement(); err != nil {
+				return fmt.Errorf("%w in `%s`", err, p.statement)
+			}
+		}
+		start = i + 1
+	}
+	return nil
+}
+
+func (p *ddlParser) parseStatement() error {
+	switch {
+	case p.accept("create"):
+		p.accept("or", "replace")
+		p.accept("global")
+		p.accept("local")
+		if p.accept("temp") || p.accept("temporary") {
+			return nil
+		}
+		p.accept("unlogged")
+		if p.accept("table") {
+			return p.parseCreateTable()
+		}
+		p.accept("materialized")
+		p.accept("recursive")
+		if p.accept("view") {
+			return p.parseCreateView()
+		}
+		isUnique := p.accept("unique")
+		if p.accept("index") {
+			return p.parseCreateIndex(isUnique)
+		}
+	case p.accept("alter", "table"):
+		return p.parseAlterTable()
+	case p.accept("comment", "on"):
+		return p.parseComment()
+	case p.accept("drop", "table"):
+		return p.parseDropTable()
+	case p.accept("set", "search_path"):
+		if !p.accept("to") {
+			p.accept("=")
+		}
+		if !p.atEnd() {
+			schemaName, err := p.identifier()
+			if err != nil {
+				return err
+			}
+			p.defaultSchema = schemaName
+		}
+	}
+	return nil
+}
+
+// parseCreateTable handles `CREATE TABLE [IF NOT EXISTS] name (...)`.
+// With `IF NOT EXISTS` an existing table is left as it is.
+func (p *ddlParser) parseCreateTable() error {
+	ifNotExists := p.accept("if", "not", "exists")
+	schemaName, tableName, err := p.tableName()
+	if err != nil {
+		return err
+	}
+	if !p.peekIs("(") {
+		// For example `CREATE TABLE ... AS SELECT` or `PARTITION OF`.
+		return nil
+	}
+	p.next()
+	if existing := p.find(schemaName, tableName); existing != nil {
+		if ifNotExists {
+			return nil
+		}
+		return fmt.Errorf("table `%s.%s` is created twice", schemaName, tableName)
+	}
+	t := &ddlTable{schemaName: schemaName, tableName: tableName}
+	for !p.accept(")") {
+		if p.atEnd() {
+			return fmt.Errorf("unexpected end of table `%s.%s`", schemaName, tableName)
+		}
+		if p.isTableConstraint() {
+			err = p.parseTableConstraint(t)
+		} else if p.accept("like") {
+			p.skipElement()
+		} else {
+			err = p.parseColumn(t)
+		}
+		if err != nil {
+			return err
+		}
+		p.accept(",")
+	}
+	p.tables = append(p.tables, t)
+	return nil
+}
+
+// parseCreateView records the name of a view, whose definition is ignored.
+func (p *ddlParser) parseCreateView() error {
+	p.accept("if", "not", "exists")
+	schemaName, viewName, err := p.tableName()
+	if err != nil {
+		return err
+	}
+	p.views[schemaName+"."+viewName] = true
+	return nil
+}
+
+// parseAlterTable handles the `ALTER TABLE` actions which change the model.
+// Those which don't (eg `OWNER TO`) are ignored, and any others are an error
+// rather than leaving the model silently out of step with the database.
+func (p *ddlParser) parseAlterTable() error {
+	ifExists := p.accept("if", "exists")
+	p.accept("only")
+	schemaName, tableName, err := p.tableName()
+	if err != nil {
+		return err
+	}
+	t := p.relation(schemaName, tableName, ifExists)
+	if t == nil {
+		return nil
+	}
+
+	// These can't be combined with other actions.
+	switch {
+	case p.accept("rename", "constraint"):
+		return p.parseRename(func(from string, to string) error { return p.renameConstraint(t, from, to) })
+	case p.accept("rename", "to"):
+		name, err := p.identifier()
+		if err != nil {
+			return err
+		}
+		return p.moveTable(t, t.schemaName, name)
+	case p.accept("rename"):
+		p.accept("column")
+		return p.parseRename(func(from string, to string) error { return p.renameColumn(t, from, to) })
+	case p.accept("set", "schema"):
+		name, err := p.identifier()
+		if err != nil {
+			return err
+		}
+		return p.moveTable(t, name, t.tableName)
+	}
+
+	for !p.atEnd() {
+		if err := p.parseAlterAction(t); err != nil {
+			return err
+		}
+		if !p.atEnd() && !p.accept(",") {
+			return fmt.Errorf("unexpected `%s`", p.peekAt(0).text)
+		}
+	}
+	return nil
+}
+
+// parseAlterAction reads one of the comma-separated actions of an `ALTER TABLE`.
+func (p *ddlParser) parseAlterAction(t *ddlTable) error {
+	switch {
+	case p.accept("add"):
+		if p.isTableConstraint() {
+			return p.parseTableConstraint(t)
+		}
+		p.accept("column")
+		if p.accept("if", "not", "exists") && p.isIdentifier() {
+			if name, _ := p.identifier(); t.column(name) != nil {
+				p.skipElement()
+				return nil
+			}
+			p.pos--
+		}
+		return p.parseColumn(t)
+	case p.accept("drop", "constraint"):
+		ifExists := p.accept("if", "exists")
+		name, err := p.identifier()
+		if err != nil {
+			return err
+		}
+		p.accept("restrict")
+		p.accept("cascade")
+		if !t.dropConstraint(name) && !ifExists {
+			return fmt.Errorf("constraint `%s` does not exist on `%s.%s`", name, t.schemaName, t.tableName)
+		}
+		return nil
+	case p.accept("drop"):
+		p.accept("column")
+		ifExists := p.accept("if", "exists")
+		name, err := p.identifier()
+		if err != nil {
+			return err
+		}
+		p.accept("restrict")
+		cascade := p.accept("cascade")
+		if t.column(name) == nil {
+			if ifExists {
+				return nil
+			}
+			return fmt.Errorf("column `%s` does not exist on `%s.%s`", name, t.schemaName, t.tableName)
+		}
+		return p.dropColumn(t, name, cascade)
+	case p.accept("alter", "constraint"):
+		// Only deferrability can be changed.
+		p.skipElement()
+		return nil
+	case p.accept("alter"):
+		p.accept("column")
+		return p.parseAlterColumn(t)
+	case p.isIgnoredAlterAction():
+		p.skipElement()
+		return nil
+	}
+	return fmt.Errorf("unsupported ALTER TABLE action `%s`", joinTokens(p.takeUntil(func(token sqlToken) bool { return token.is(",") })))
+}
+
+// isIgnoredAlterAction returns true if an `ALTER TABLE` action which doesn't
+// change the model comes next, such as `OWNER TO` or `ENABLE TRIGGER`.
+func (p *ddlParser) isIgnoredAlterAction() bool {
+	for _, words := range [][]string{
+		{"owner", "to"}, {"enable"}, {"disable"}, {"force"}, {"no", "force"},
+		{"set", "tablespace"}, {"set", "logged"}, {"set", "unlogged"}, {"set", "without"},
+		{"set", "access", "method"}, {"set", "("}, {"reset", "("}, {"cluster", "on"},
+		{"replica", "identity"}, {"validate", "constraint"}, {"inherit"}, {"no", "inherit"},
+		{"of"}, {"not", "of"}, {"attach", "partition"}, {"detach", "partition"},
+	} {
+		matches := true
+		for i, word := range words {
+			matches = matches && p.peekAt(i).is(word)
+		}
+		if matches {
+			return true
+		}
+	}
+	return false
+}
+
+// parseAlterColumn handles changes to a column's type, nullability, or default.
+// Changes to its storage, statistics, identity etc don't affect the model.
+func (p *ddlParser) parseAlterColumn(t *ddlTable) error {
+	name, err := p.identifier()
+	if err != nil {
+		return err
+	}
+	col := t.column(name)
+	if col == nil {
+		return fmt.Errorf("column `%s` does not exist on `%s.%s`", name, t.schemaName, t.tableName)
+	}
+	switch {
+	case p.accept("type"), p.accept("set", "data", "type"):
+		words, args, err := p.typeName()
+		if err != nil {
+			return err
+		}
+		changed, isSerial, err := newDDLColumn(name, words, args)
+		if err != nil {
+			return err
+		}
+		if isSerial {
+			return fmt.Errorf("column `%s` cannot be changed to a serial type", name)
+		}
+		col.dataType, col.maxLen, col.numericPrecision = changed.dataType, changed.maxLen, changed.numericPrecision
+		if p.accept("collate") {
+			if _, err := p.dottedName(); err != nil {
+				return err
+			}
+		}
+		if p.accept("using") {
+			p.skipElement()
+		}
+	case p.accept("set", "not", "null"):
+		col.isNullable = false
+	case p.accept("drop", "not", "null"):
+		col.isNullable = true
+	case p.accept("set", "default"):
+		def := joinTokens(p.takeUntil(func(token sqlToken) bool { return token.is(",") }))
+		col.columnDefault = &def
+	case p.accept("drop", "default"):
+		col.columnDefault = nil
+	case p.accept("set"), p.accept("reset"), p.accept("add", "generated"), p.accept("drop", "identity"), p.accept("drop", "expression"):
+		p.skipElement()
+	default:
+		return fmt.Errorf("unsupported ALTER COLUMN action for `%s`", name)
+	}
+	return nil
+}
+
+// parseRename reads the `from TO to` of a rename, and applies it.
+func (p *ddlParser) parseRename(rename func(from string, to string) error) error {
+	from, err := p.identifier()
+	if err != nil {
+		return err
+	}
+	if !p.accept("to") {
+		return fmt.Errorf("expected TO")
+	}
+	to, err := p.identifier()
+	if err != nil {
+		return err
+	}
+	return rename(from, to)
+}
+
+// renameColumn renames a column, including in any constraints and indexes
+// using it and any foreign keys (in any table) referencing it.
+func (p *ddlParser) renameColumn(t *ddlTable, from string, to string) error {
+	col := t.column(from)
+	if col == nil {
+		return fmt.Errorf("column `%s` does not exist on `%s.%s`", from, t.schemaName, t.tableName)
+	}
+	if t.column(to) != nil {
+		return fmt.Errorf("column `%s` already exists on `%s.%s`", to, t.schemaName, t.tableName)
+	}
+	col.name = to
+	for i := range t.constraints {
+		replaceName(t.constraints[i].ColumnNames, from, to)
+	}
+	for i := range t.indexes {
+		replaceName(t.indexes[i].ColumnNames, from, to)
+	}
+	for _, other := range p.tables {
+		for i := range other.constraints {
+			c := &other.constraints[i]
+			if c.ForeignColumn != nil && referencesColumn(*c, t, from) {
+				c.ForeignColumn = &to
+			}
+		}
+	}
+	return nil
+}
+
+// renameConstraint renames a constraint, along with the index for a primary
+// or unique key (as Postgres does).
+func (p *ddlParser) renameConstraint(t *ddlTable, from string, to string) error {
+	for i, c := range t.constraints {
+		if c.ConstraintName != from {
+			continue
+		}
+		renamed := newConstraint(p.namer, to, c.ConstraintType, c.ColumnNames)
+		renamed.ForeignSchema, renamed.ForeignTable, renamed.ForeignColumn = c.ForeignSchema, c.ForeignTable, c.ForeignColumn
+		t.constraints[i] = renamed
+		for j, idx := range t.indexes {
+			if idx.IndexName == from {
+				t.indexes[j] = newIndex(p.namer, to, idx.IsPrimaryKey, idx.IsUnique, idx.ColumnNames)
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("constraint `%s` does not exist on `%s.%s`", from, t.schemaName, t.tableName)
+}
+
+// moveTable renames a table and/or changes its schema, updating any foreign
+// keys which reference it.
+func (p *ddlParser) moveTable(t *ddlTable, schemaName string, tableName string) error {
+	if existing := p.find(schemaName, tableName); existing != nil && existing != t {
+		return fmt.Errorf("table `%s.%s` already exists", schemaName, tableName)
+	}
+	for _, other := range p.tables {
+		for i := range other.constraints {
+			c := &other.constraints[i]
+			if references(*c, t) {
+				c.ForeignSchema, c.ForeignTable = &schemaName, &tableName
+			}
+		}
+	}
+	t.schemaName, t.tableName = schemaName, tableName
+	return nil
+}
+
+// dropColumn removes a column, along with any constraints and indexes using
+// it (as Postgres does). Foreign keys in other tables referencing it are only
+// removed with `CASCADE`, and are otherwise an error.
+func (p *ddlParser) dropColumn(t *ddlTable, name string, cascade bool) error {
+	for _, other := range p.tables {
+		dependent := []string{}
+		for _, c := range other.constraints {
+			if other == t && containsName(c.ColumnNames, name) {
+				// Dropped along with the column anyway.
+				continue
+			}
+			if referencesColumn(c, t, name) {
+				if !cascade {
+					return fmt.Errorf("column `%s` is referenced by foreign key `%s` on `%s.%s` (use CASCADE)",
+						name, c.ConstraintName, other.schemaName, other.tableName)
+				}
+				dependent = append(dependent, c.ConstraintName)
+			}
+		}
+		for _, constraintName := range dependent {
+			other.dropConstraint(constraintName)
+		}
+	}
+	columns := []ddlColumn{}
+	for _, col := range t.columns {
+		if col.name != name {
+			columns = append(columns, col)
+		}
+	}
+	t.columns = columns
+	constraints := []model.Constraint{}
+	for _, c := range t.constraints {
+		if !containsName(c.ColumnNames, name) {
+			constraints = append(constraints, c)
+		}
+	}
+	t.constraints = constraints
+	indexes := []model.Index{}
+	for _, idx := range t.indexes {
+		if !containsName(idx.ColumnNames, name) {
+			indexes = append(indexes, idx)
+		}
+	}
+	t.indexes = indexes
+	return nil
+}
+
+// dropConstraint removes a constraint, along with the index for a primary or
+// unique key. It returns false if there was no such constraint.
+func (t *ddlTable) dropConstraint(name string) bool {
+	for i, c := range t.constraints {
+		if c.ConstraintName != name {
+			continue
+		}
+		t.constraints = append(t.constraints[:i], t.constraints[i+1:]...)
+		for j, idx := range t.indexes {
+			if idx.IndexName == name {
+				t.indexes = append(t.indexes[:j], t.indexes[j+1:]...)
+				break
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// parseCreateIndex handles `CREATE [UNIQUE] INDEX [name] ON table (...)`.
+// Expressions are not plain columns so are left out, as when scanning.
+// With `IF NOT EXISTS` an existing index is left as it is.
+func (p *ddlParser) parseCreateIndex(isUnique bool) error {
+	p.accept("concurrently")
+	ifNotExists := p.accept("if", "not", "exists")
+	name := ""
+	if !p.peekIs("on") {
+		n, err := p.identifier()
+		if err != nil {
+			return err
+		}
+		name = n
+	}
+	if !p.accept("on") {
+		return fmt.Errorf("expected ON")
+	}
+	p.accept("only")
+	schemaName, tableName, err := p.tableName()
+	if err != nil {
+		return err
+	}
+	t := p.relation(schemaName, tableName, false)
+	if t == nil {
+		return nil
+	}
+	if p.accept("using") {
+		p.next()
+	}
+	if !p.accept("(") {
+		return fmt.Errorf("expected index columns")
+	}
+	columnNames := []string{}
+	for !p.accept(")") {
+		if p.atEnd() {
+			return fmt.Errorf("unexpected end of index")
+		}
+		if p.isIdentifier() && !p.peekAt(1).is("(") {
+			columnName, _ := p.identifier()
+			columnNames = append(columnNames, columnName)
+		}
+		p.skipElement()
+		p.accept(",")
+	}
+	if len(columnNames) == 0 {
+		return nil
+	}
+	if len(name) == 0 {
+		name = tableName + "_" + strings.Join(columnNames, "_") + "_idx"
+	} else if p.hasIndex(schemaName, name) {
+		if ifNotExists {
+			return nil
+		}
+		return fmt.Errorf("index `%s.%s` is created twice", schemaName, name)
+	}
+	t.indexes = append(t.indexes, newIndex(p.namer, name, false, isUnique, columnNames))
+	return nil
+}
+
+// parseComment handles `COMMENT ON TABLE` and `COMMENT ON COLUMN`.
+func (p *ddlParser) parseComment() error {
+	isTable := p.accept("table")
+	if !isTable && !p.accept("column") {
+		return nil
+	}
+	parts, err := p.dottedName()
+	if err != nil {
+		return err
+	}
+	columnName := ""
+	if !isTable {
+		if len(parts) < 2 {
+			return fmt.Errorf("expected a table and column name")
+		}
+		columnName, parts = parts[len(parts)-1], parts[:len(parts)-1]
+	}
+	schemaName, tableName := p.qualify(parts)
+	t := p.relation(schemaName, tableName, false)
+	if t == nil {
+		return nil
+	}
+	if !p.accept("is") {
+		return fmt.Errorf("expected IS")
+	}
+	comment := ""
+	if token := p.next(); token.kind == sqlString {
+		comment = token.text
+	}
+	if isTable {
+		t.comment = comment
+		return nil
+	}
+	col := t.column(columnName)
+	if col == nil {
+		return fmt.Errorf("column `%s.%s.%s` has not been created", schemaName, tableName, columnName)
+	}
+	col.comment = comment
+	return nil
+}
+
+// parseDropTable handles `DROP TABLE [IF EXISTS] name [, ...]`.
+func (p *ddlParser) parseDropTable() error {
+	p.accept("if", "exists")
+	for !p.atEnd() && p.isIdentifier() {
+		schemaName, tableName, err := p.tableName()
+		if err != nil {
+			return err
+		}
+		for i, t := range p.tables {
+			if t.schemaName == schemaName && t.tableName == tableName {
+				p.tables = append(p.tables[:i], p.tables[i+1:]...)
+				break
+			}
+		}
+		p.accept(",")
+	}
+	return nil
+}
+
+// parseColumn reads a column definition and any column constraints.
+func (p *ddlParser) parseColumn(t *ddlTable) error {
+	name, err := p.identifier()
+	if err != nil {
+		return err
+	}
+	if t.column(name) != nil {
+		return fmt.Errorf("column `%s` is defined twice", name)
+	}
+	words, args, err := p.typeName()
+	if err != nil {
+		return err
+	}
+	col, isSerial, err := newDDLColumn(name, words, args)
+	if err != nil {
+		return err
+	}
+	if isSerial {
+		def := fmt.Sprintf("nextval('%s_%s_seq'::regclass)", t.tableName, name)
+		col.columnDefault = &def
+	}
+	t.lastPosition++
+	col.position = t.lastPosition
+	t.columns = append(t.columns, col)
+
+	constraintName := ""
+	for !p.atEnd() && !p.peekIs(",") && !p.peekIs(")") {
+		switch {
+		case p.accept("constraint"):
+			constraintName, err = p.identifier()
+			if err != nil {
+				return err
+			}
+			continue
+		case p.accept("not", "null"):
+			t.column(name).isNullable = false
+		case p.accept("null"):
+			t.column(name).isNullable = true
+		case p.accept("default"):
+			def := joinTokens(p.takeUntil(isColumnConstraintStart))
+			t.column(name).columnDefault = &def
+		case p.accept("primary", "key"):
+			p.addConstraint(t, newConstraint(p.namer, orDefault(constraintName, t.tableName+"_pkey"), "PRIMARY KEY", []string{name}))
+			p.skipIndexParameters()
+		case p.accept("unique"):
+			p.accept("nulls", "not", "distinct")
+			p.accept("nulls", "distinct")
+			p.addConstraint(t, newConstraint(p.namer, orDefault(constraintName, t.tableName+"_"+name+"_key"), "UNIQUE", []string{name}))
+			p.skipIndexParameters()
+		case p.accept("references"):
+			c := newConstraint(p.namer, orDefault(constraintName, t.tableName+"_"+name+"_fkey"), "FOREIGN KEY", []string{name})
+			if err := p.parseReferences(&c); err != nil {
+				return err
+			}
+			p.addConstraint(t, c)
+		case p.accept("check"):
+			p.skipGroup()
+			p.accept("no", "inherit")
+		case p.accept("collate"):
+			if _, err := p.dottedName(); err != nil {
+				return err
+			}
+		case p.accept("generated"):
+			isIdentity, err := p.parseGenerated()
+			if err != nil {
+				return err
+			}
+			if isIdentity {
+				t.column(name).isNullable = false
+			}
+		default:
+			p.next()
+		}
+		constraintName = ""
+	}
+	return nil
+}
+
+// parseGenerated reads the rest of an identity column's `GENERATED { ALWAYS |
+// BY DEFAULT } AS IDENTITY [ ( options ) ]`, or a generated column's
+// `GENERATED ALWAYS AS ( expr ) STORED`. Neither has a default value as such,
+// but identity columns are never null. It returns true for an identity column.
+func (p *ddlParser) parseGenerated() (bool, error) {
+	if !p.accept("always") && !p.accept("by", "default") {
+		return false, fmt.Errorf("expected ALWAYS or BY DEFAULT after GENERATED")
+	}
+	if !p.accept("as") {
+		return false, fmt.Errorf("expected AS after GENERATED")
+	}
+	if p.accept("identity") {
+		p.skipGroup()
+		return true, nil
+	}
+	if !p.peekIs("(") {
+		return false, fmt.Errorf("expected IDENTITY or an expression after GENERATED")
+	}
+	p.skipGroup()
+	if !p.accept("stored") && !p.accept("virtual") {
+		return false, fmt.Errorf("expected STORED after a generated column's expression")
+	}
+	return false, nil
+}
+
+// isTableConstraint returns true if a table constraint comes next.
+func (p *ddlParser) isTableConstraint() bool {
+	for _, word := range []string{"constraint", "primary", "unique", "foreign", "check", "exclude"} {
+		if p.peekIs(word) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTableConstraint reads a primary, unique, or foreign key. Others are skipped.
+// Default names follow the Postgres conventions.
+func (p *ddlParser) parseTableConstraint(t *ddlTable) error {
+	name := ""
+	if p.accept("constraint") {
+		n, err := p.identifier()
+		if err != nil {
+			return err
+		}
+		name = n
+	}
+	switch {
+	case p.accept("primary", "key"):
+		columnNames, err := p.columnList()
+		if err != nil {
+			return err
+		}
+		p.addConstraint(t, newConstraint(p.namer, orDefault(name, t.tableName+"_pkey"), "PRIMARY KEY", columnNames))
+	case p.accept("unique"):
+		p.accept("nulls", "not", "distinct")
+		p.accept("nulls", "distinct")
+		columnNames, err := p.columnList()
+		if err != nil {
+			return err
+		}
+		p.addConstraint(t, newConstraint(p.namer, orDefault(name, t.tableName+"_"+strings.Join(columnNames, "_")+"_key"), "UNIQUE", columnNames))
+	case p.accept("foreign", "key"):
+		columnNames, err := p.columnList()
+		if err != nil {
+			return err
+		}
+		if !p.accept("references") {
+			return fmt.Errorf("expected REFERENCES")
+		}
+		c := newConstraint(p.namer, orDefault(name, t.tableName+"_"+strings.Join(columnNames, "_")+"_fkey"), "FOREIGN KEY", columnNames)
+		if err := p.parseReferences(&c); err != nil {
+			return err
+		}
+		p.addConstraint(t, c)
+	}
+	p.skipElement()
+	return nil
+}
+
+// parseReferences reads the target of a foreign key, and skips any actions.
+func (p *ddlParser) parseReferences(c *model.Constraint) error {
+	schemaName, tableName, err := p.tableName()
+	if err != nil {
+		return err
+	}
+	c.ForeignSchema, c.ForeignTable = &schemaName, &tableName
+	if p.peekIs("(") {
+		columnNames, err := p.columnList()
+		if err != nil {
+			return err
+		}
+		c.ForeignColumn = &columnNames[0]
+	}
+	for {
+		switch {
+		case p.accept("match"):
+			p.next()
+		case p.accept("on", "delete"), p.accept("on", "update"):
+			if p.accept("set", "null") || p.accept("set", "default") {
+				if p.peekIs("(") {
+					p.skipGroup()
+				}
+			} else if !p.accept("no", "action") {
+				p.next()
+			}
+		case p.accept("deferrable"), p.accept("not", "deferrable"):
+		case p.accept("initially"):
+			p.next()
+		default:
+			return nil
+		}
+	}
+}
+
+// skipIndexParameters skips any `INCLUDE`, `WITH`, or `USING INDEX TABLESPACE` clauses.
+func (p *ddlParser) skipIndexParameters() {
+	for {
+		switch {
+		case p.accept("include"), p.accept("with"):
+			p.skipGroup()
+		case p.accept("using", "index", "tablespace"):
+			p.next()
+		default:
+			return
+		}
+	}
+}
+
+// typeName reads a (possibly multi-word) type name and any arguments,
+// for example `timestamp(3) with time zone` or `numeric(10, 2)`.
+func (p *ddlParser) typeName() ([]string, []int, error) {
+	words, args := []string{}, []int{}
+	for !p.atEnd() && !isColumnConstraintStart(p.peekAt(0)) {
+		token := p.peekAt(0)
+		switch {
+		case token.is("("):
+			p.next()
+			for !p.accept(")") {
+				if p.atEnd() {
+					return nil, nil, fmt.Errorf("unexpected end of type")
+				}
+				if n, err := strconv.Atoi(p.next().text); err == nil {
+					args = append(args, n)
+				}
+			}
+		case token.is("["):
+			return nil, nil, fmt.Errorf("unsupported array type `%s[]`", strings.Join(words, " "))
+		case token.is("."):
+			// Qualified types such as `pg_catalog.int4`.
+			p.next()
+			words = words[:0]
+		case token.kind == sqlWord || token.kind == sqlQuoted:
+			words = append(words, strings.ToLower(p.next().text))
+		default:
+			return nil, nil, fmt.Errorf("unexpected `%s` in type", token.text)
+		}
+	}
+	if len(words) == 0 {
+		return nil, nil, fmt.Errorf("missing type")
+	}
+	return words, args, nil
+}
+
+// newDDLColumn maps a declared type onto the names used by `information_schema`
+// (and so by the scanner), along with the maximum length or numeric precision.
+func newDDLColumn(name string, words []string, args []int) (ddlColumn, bool, error) {
+	col := ddlColumn{name: name, isNullable: true}
+	arg := func() *int {
+		if len(args) == 0 {
+			return nil
+		}
+		return &args[0]
+	}
+	precision := func(n int) *int {
+		return &n
+	}
+	isSerial := false
+	typeName := strings.Join(words, " ")
+	switch typeName {
+	case "smallint", "int2":
+		col.dataType, col.numericPrecision = "smallint", precision(16)
+	case "integer", "int", "int4":
+		col.dataType, col.numericPrecision = "integer", precision(32)
+	case "bigint", "int8":
+		col.dataType, col.numericPrecision = "bigint", precision(64)
+	case "smallserial", "serial2":
+		col.dataType, col.numericPrecision, isSerial = "smallint", precision(16), true
+	case "serial", "serial4":
+		col.dataType, col.numericPrecision, isSerial = "integer", precision(32), true
+	case "bigserial", "serial8":
+		col.dataType, col.numericPrecision, isSerial = "bigint", precision(64), true
+	case "numeric", "decimal":
+		col.dataType, col.numericPrecision = "numeric", arg()
+	case "real", "float4":
+		col.dataType, col.numericPrecision = "real", precision(24)
+	case "double precision", "float8":
+		col.dataType, col.numericPrecision = "double precision", precision(53)
+	case "float":
+		col.dataType, col.numericPrecision = "double precision", precision(53)
+		if len(args) > 0 && args[0] <= 24 {
+			col.dataType, col.numericPrecision = "real", precision(24)
+		}
+	case "money", "text", "bytea", "date", "interval", "uuid", "json", "jsonb", "xml":
+		col.dataType = typeName
+	case "boolean", "bool":
+		col.dataType = "boolean"
+	case "character varying", "varchar":
+		col.dataType, col.maxLen = "character varying", arg()
+	case "character", "char", "bpchar":
+		col.dataType, col.maxLen = "character", arg()
+		if col.maxLen == nil {
+			col.maxLen = precision(1)
+		}
+	case "timestamp", "timestamp without time zone":
+		col.dataType = "timestamp without time zone"
+	case "timestamptz", "timestamp with time zone":
+		col.dataType = "timestamp with time zone"
+	case "time", "time without time zone":
+		col.dataType = "time without time zone"
+	case "timetz", "time with time zone":
+		col.dataType = "time with time zone"
+	case "bit":
+		return col, false, fmt.Errorf("unsupported column type 'bit' for `%s` - use 'boolean' instead", name)
+	default:
+		return col, false, fmt.Errorf("unsupported type `%s` for column `%s`", typeName, name)
+	}
+	return col, isSerial, nil
+}
+
+// isColumnConstraintStart returns true for tokens ending a column's type or default.
+func isColumnConstraintStart(token sqlToken) bool {
+	if token.is(",") || token.is(")") {
+		return true
+	}
+	if token.kind != sqlWord {
+		return false
+	}
+	switch strings.ToLower(token.text) {
+	case "constraint", "not", "null", "default", "primary", "unique", "references", "check", "collate", "generated", "using":
+		return true
+	}
+	return false
+}
+
+// ---------- Token handling ----------
+
+// tableName reads a possibly schema-qualified table name.
+func (p *ddlParser) tableName() (string, string, error) {
+	parts, err := p.dottedName()
+	if err != nil {
+		return "", "", err
+	}
+	if len(parts) > 2 {
+		return "", "", fmt.Errorf("unexpected name `%s`", strings.Join(parts, "."))
+	}
+	schemaName, tableName := p.qualify(parts)
+	return schemaName, tableName, nil
+}
+
+// qualify returns the schema and table for a one or two part name.
+func (p *ddlParser) qualify(parts []string) (string, string) {
+	if len(parts) == 1 {
+		return p.defaultSchema, parts[0]
+	}
+	return parts[len(parts)-2], parts[len(parts)-1]
+}
+
+// dottedName reads an identifier with any dot-separated parts.
+func (p *ddlParser) dottedName() ([]string, error) {
+	parts := []string{}
+	for {
+		part, err := p.identifier()
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, part)
+		if !p.accept(".") {
+			return parts, nil
+		}
+	}
+}
+
+// identifier reads a name. Unquoted names are folded to lower case, as Postgres does.
+func (p *ddlParser) identifier() (string, error) {
+	if !p.isIdentifier() {
+		if p.atEnd() {
+			return "", fmt.Errorf("expected a name")
+		}
+		return "", fmt.Errorf("expected a name, not `%s`", p.peekAt(0).text)
+	}
+	token := p.next()
+	if token.kind == sqlQuoted {
+		return token.text, nil
+	}
+	return strings.ToLower(token.text), nil
+}
+
+func (p *ddlParser) isIdentifier() bool {
+	token := p.peekAt(0)
+	return token.kind == sqlQuoted || (token.kind == sqlWord && len(token.text) > 0)
+}
+
+// columnList reads a parenthesised list of column names.
+func (p *ddlParser) columnList() ([]string, error) {
+	if !p.accept("(") {
+		return nil, fmt.Errorf("expected a column list")
+	}
+	names := []string{}
+	for !p.accept(")") {
+		name, err := p.identifier()
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+		p.accept(",")
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("empty column list")
+	}
+	return names, nil
+}
+
+// skipGroup skips a parenthesised group, including any nested ones.
+func (p *ddlParser) skipGroup() {
+	if !p.accept("(") {
+		return
+	}
+	for depth := 1; depth > 0 && !p.atEnd(); {
+		token := p.next()
+		if token.is("(") {
+			depth++
+		} else if token.is(")") {
+			depth--
+		}
+	}
+}
+
+// skipElement skips to the next top-level comma or closing parenthesis.
+func (p *ddlParser) skipElement() {
+	p.takeUntil(func(token sqlToken) bool { return token.is(",") || token.is(")") })
+}
+
+// takeUntil returns the tokens up to (but not including) the first top-level one matching.
+func (p *ddlParser) takeUntil(stop func(sqlToken) bool) []sqlToken {
+	taken := []sqlToken{}
+	depth := 0
+	for !p.atEnd() {
+		token := p.peekAt(0)
+		if depth == 0 && stop(token) {
+			break
+		}
+		if token.is("(") {
+			depth++
+		} else if token.is(")") {
+			depth--
+		}
+		taken = append(taken, p.next())
+	}
+	return taken
+}
+
+// accept consumes the words/symbols if they are next, returning whether they were.
+func (p *ddlParser) accept(words ...string) bool {
+	for i, word := range words {
+		if !p.peekAt(i).is(word) {
+			return false
+		}
+	}
+	p.pos += len(words)
+	return true
+}
+
+func (p *ddlParser) peekIs(word string) bool {
+	return p.peekAt(0).is(word)
+}
+
+func (p *ddlParser) peekAt(offset int) sqlToken {
+	if p.pos+offset >= len(p.tokens) {
+		return sqlToken{kind: sqlSymbol}
+	}
+	return p.tokens[p.pos+offset]
+}
+
+func (p *ddlParser) next() sqlToken {
+	token := p.peekAt(0)
+	if !p.atEnd() {
+		p.pos++
+	}
+	return token
+}
+
+func (p *ddlParser) atEnd() bool {
+	return p.pos >= len(p.tokens)
+}
+
+// is returns true for a matching (case-insensitive) word or symbol.
+func (t sqlToken) is(text string) bool {
+	return (t.kind == sqlWord || t.kind == sqlSymbol) && strings.EqualFold(t.text, text)
+}
+
+// tokenizeSQL splits SQL into tokens, dropping whitespace and comments.
+func tokenizeSQL(text string) ([]sqlToken, error) {
+	tokens := []sqlToken{}
+	runes := []rune(text)
+	isWordRune := func(r rune) bool {
+		return r == '_' || r == '$' || (r >= '0' && r <= '9') || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || r > 127
+	}
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		rest := string(r)
+		if i+1 < len(runes) {
+			rest += string(runes[i+1])
+		}
+		switch {
+		case r == ' ' || r == '\t' || r == '\r' || r == '\n':
+			i++
+		case rest == "--":
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case rest == "/*":
+			end := strings.Index(string(runes[i+2:]), "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated comment")
+			}
+			i += 2 + len([]rune(string(runes[i+2:])[:end])) + 2
+		case r == '\'' || r == '"':
+			value, n, ok := readQuoted(runes[i:], r)
+			if !ok {
+				return nil, fmt.Errorf("unterminated %c quote", r)
+			}
+			kind := sqlString
+			if r == '"' {
+				kind = sqlQuoted
+			}
+			tokens = append(tokens, sqlToken{kind: kind, text: value})
+			i += n
+		case (r == 'E' || r == 'e') && i+1 < len(runes) && runes[i+1] == '\'':
+			value, n, ok := readQuoted(runes[i+1:], '\'')
+			if !ok {
+				return nil, fmt.Errorf("unterminated ' quote")
+			}
+			tokens = append(tokens, sqlToken{kind: sqlString, text: value})
+			i += n + 1
+		case r == '$' && (i+1 < len(runes)) && (runes[i+1] == '$' || !(runes[i+1] >= '0' && runes[i+1] <= '9')):
+			// Dollar quotes, such as `$$` or `$body$`.
+			j := i + 1
+			for j < len(runes) && runes[j] != '$' && isWordRune(runes[j]) {
+				j++
+			}
+			if j >= len(runes) || runes[j] != '$' {
+				return nil, fmt.Errorf("unterminated dollar quote")
+			}
+			tag := string(runes[i : j+1])
+			end := strings.Index(string(runes[j+1:]), tag)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated %s quote", tag)
+			}
+			value := string(runes[j+1:])[:end]
+			tokens = append(tokens, sqlToken{kind: sqlString, text: value})
+			i = j + 1 + len([]rune(value)) + len([]rune(tag))
+		case isWordRune(r):
+			j := i
+			for j < len(runes) && isWordRune(runes[j]) {
+				j++
+			}
+			// Decimal numbers are kept together.
+			if j+1 < len(runes) && runes[j] == '.' && runes[j+1] >= '0' && runes[j+1] <= '9' && r >= '0' && r <= '9' {
+				for j++; j < len(runes) && isWordRune(runes[j]); j++ {
+				}
+			}
+			tokens = append(tokens, sqlToken{kind: sqlWord, text: string(runes[i:j])})
+			i = j
+		case rest == "::":
+			tokens = append(tokens, sqlToken{kind: sqlSymbol, text: rest})
+			i += 2
+		default:
+			tokens = append(tokens, sqlToken{kind: sqlSymbol, text: string(r)})
+			i++
+		}
+	}
+	return tokens, nil
+}
+
+// readQuoted reads a quoted value where doubled quotes are escapes.
+// It returns the value, the number of runes read, and whether it was terminated.
+func readQuoted(runes []rune, quote rune) (string, int, bool) {
+	var sb strings.Builder
+	for i := 1; i < len(runes); i++ {
+		if runes[i] == quote {
+			if i+1 < len(runes) && runes[i+1] == quote {
+				sb.WriteRune(quote)
+				i++
+				continue
+			}
+			return sb.String(), i + 1, true
+		}
+		sb.WriteRune(runes[i])
+	}
+	return "", 0, false
+}
+
+// joinTokens turns tokens back into SQL text, for example for column defaults.
+func joinTokens(tokens []sqlToken) string {
+	var sb strings.Builder
+	for i, token := range tokens {
+		text := token.text
+		switch token.kind {
+		case sqlString:
+			text = "'" + strings.ReplaceAll(text, "'", "''") + "'"
+		case sqlQuoted:
+			text = mapping.QuoteIdentifier(text)
+		}
+		if i > 0 {
+			previous := tokens[i-1]
+			tight := previous.is("(") || previous.is("::") || previous.is(".") ||
+				token.is(")") || token.is(",") || token.is("::") || token.is(".") ||
+				(token.is("(") && (previous.kind == sqlWord || previous.kind == sqlQuoted))
+			if !tight {
+				sb.WriteString(" ")
+			}
+		}
+		sb.WriteString(text)
+	}
+	return sb.String()
+}
+
+// summariseTokens returns the start of a statement, for error messages.
+func summariseTokens(tokens []sqlToken) string {
+	if len(tokens) > 8 {
+		return joinTokens(tokens[:8]) + " ..."
+	}
+	return joinTokens(tokens)
+}
+
+// references returns true if the constraint is a foreign key to the table.
+func references(c model.Constraint, t *ddlTable) bool {
+	return c.IsForeignKey && c.ForeignSchema != nil && c.ForeignTable != nil &&
+		*c.ForeignSchema == t.schemaName && *c.ForeignTable == t.tableName
+}
+
+// referencesColumn returns true if the constraint is a foreign key to the
+// column, either explicitly or as the table's primary key.
+func referencesColumn(c model.Constraint, t *ddlTable, name string) bool {
+	if !references(c, t) {
+		return false
+	}
+	if c.ForeignColumn != nil {
+		return *c.ForeignColumn == name
+	}
+	pk := t.primaryKey()
+	return pk != nil && pk.ColumnNames[0] == name
+}
+
+// replaceName changes any occurrences of a name in the list.
+func replaceName(names []string, from string, to string) {
+	for i := range names {
+		if names[i] == from {
+			names[i] = to
+		}
+	}
+}
+
+// containsName returns true if the name is in the list.
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// orDefault returns the value, or the default if the value is empty.
+func orDefault(value string, def string) string {
+	if len(value) == 0 {
+		return def
+	}
+	return value
+}
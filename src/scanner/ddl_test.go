@@ -0,0 +1,609 @@
+package scanner
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/kcartlidge/ng/src/model"
+)
+
+func TestTokenizeSQL(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want string
+		err  string
+	}{
+		{name: "words and symbols", sql: "SELECT a, b1 FROM t;", want: "w:SELECT w:a s:, w:b1 w:FROM w:t s:;"},
+		{name: "numbers", sql: "numeric(10, 2) DEFAULT 1.5", want: "w:numeric s:( w:10 s:, w:2 s:) w:DEFAULT w:1.5"},
+		{name: "casts", sql: "''::character varying", want: "v: s::: w:character w:varying"},
+		{name: "quoted identifiers", sql: `"Mixed ""Name""".x`, want: `q:Mixed "Name" s:. w:x`},
+		{name: "strings", sql: `'it''s' E'x'`, want: "v:it's v:x"},
+		{name: "dollar quotes", sql: "$$ a; 'b' $$", want: "v: a; 'b' "},
+		{name: "tagged dollar quotes", sql: "$fn$ a $$ b $fn$ x", want: "v: a $$ b  w:x"},
+		{name: "parameters are words", sql: "$1", want: "w:$1"},
+		{name: "line comments", sql: "a -- b; c\nd", want: "w:a w:d"},
+		{name: "block comments", sql: "a /* b;\nc */ d", want: "w:a w:d"},
+		{name: "unterminated string", sql: "'abc", err: "unterminated ' quote"},
+		{name: "unterminated identifier", sql: `"abc`, err: `unterminated " quote`},
+		{name: "unterminated comment", sql: "/* abc", err: "unterminated comment"},
+		{name: "unterminated dollar quote", sql: "$$ abc", err: "unterminated $$ quote"},
+		{name: "unterminated dollar tag", sql: "$abc", err: "unterminated dollar quote"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tokens, err := tokenizeSQL(test.sql)
+			if len(test.err) > 0 {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("expected error %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := describeTokens(tokens); got != test.want {
+				t.Errorf("got  %s\nwant %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestScanSQLFiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		schemas []string
+		sql     string
+		want    string
+		err     string
+		log     string
+	}{
+		{
+			name: "create table with column constraints",
+			sql: `CREATE TABLE shop.product (
+				id serial PRIMARY KEY,
+				code varchar(10) NOT NULL UNIQUE,
+				title text COLLATE "C" CHECK (length(title) > 0) DEFAULT 'x',
+				price numeric(10, 2) NOT NULL DEFAULT 0,
+				ref bigint GENERATED ALWAYS AS IDENTITY,
+				made_at timestamptz DEFAULT NOW(),
+				parent_id int CONSTRAINT product_parent REFERENCES product (id) ON DELETE SET NULL
+			);`,
+			want: `shop.product
+  1 id integer NOT NULL DEFAULT nextval('product_id_seq'::regclass)
+  2 code character varying(10) NOT NULL
+  3 title text DEFAULT 'x'
+  4 price numeric(10) NOT NULL DEFAULT 0
+  5 ref bigint NOT NULL
+  6 made_at timestamp with time zone DEFAULT NOW()
+  7 parent_id integer
+  constraint product_code_key UNIQUE (code)
+  constraint product_parent FOREIGN KEY (parent_id) REFERENCES shop.product(id)
+  constraint product_pkey PRIMARY KEY (id)
+  index product_code_key (code) unique
+  index product_pkey (id) primary unique`,
+		},
+		{
+			name: "identity generated by default",
+			sql: `CREATE TABLE shop.a (
+				id bigint GENERATED BY DEFAULT AS IDENTITY (START WITH 10 INCREMENT BY 1) PRIMARY KEY,
+				b int DEFAULT 1
+			);`,
+			want: `shop.a
+  1 id bigint NOT NULL
+  2 b integer DEFAULT 1
+  constraint a_pkey PRIMARY KEY (id)
+  index a_pkey (id) primary unique`,
+		},
+		{
+			name: "identity generated always",
+			sql:  `CREATE TABLE shop.a (id int GENERATED ALWAYS AS IDENTITY, b int);`,
+			want: `shop.a
+  1 id integer NOT NULL
+  2 b integer`,
+		},
+		{
+			name: "generated column expression",
+			sql: `CREATE TABLE shop.a (
+				price numeric NOT NULL,
+				gross numeric GENERATED ALWAYS AS (price * 1.2) STORED,
+				b int DEFAULT 1
+			);`,
+			want: `shop.a
+  1 price numeric NOT NULL
+  2 gross numeric
+  3 b integer DEFAULT 1`,
+		},
+		{
+			name: "generated column without storage",
+			sql:  `CREATE TABLE shop.a (gross numeric GENERATED ALWAYS AS (1) DEFAULT 2);`,
+			err:  "expected STORED after a generated column's expression",
+		},
+		{
+			name: "create table with table constraints",
+			sql: `CREATE TABLE shop.customer (id bigserial, PRIMARY KEY (id));
+				CREATE UNLOGGED TABLE IF NOT EXISTS shop.orders (
+					customer_id bigint,
+					line int,
+					email character varying(50),
+					CONSTRAINT orders_pk PRIMARY KEY (customer_id, line),
+					UNIQUE NULLS NOT DISTINCT (email),
+					FOREIGN KEY (customer_id) REFERENCES customer MATCH SIMPLE ON UPDATE NO ACTION ON DELETE CASCADE NOT VALID,
+					CHECK (line > 0)
+				);`,
+			want: `shop.customer
+  1 id bigint NOT NULL DEFAULT nextval('customer_id_seq'::regclass)
+  constraint customer_pkey PRIMARY KEY (id)
+  index customer_pkey (id) primary unique
+shop.orders
+  1 customer_id bigint NOT NULL
+  2 line integer NOT NULL
+  3 email character varying(50)
+  constraint orders_customer_id_fkey FOREIGN KEY (customer_id) REFERENCES shop.customer(id)
+  constraint orders_email_key UNIQUE (email)
+  constraint orders_pk PRIMARY KEY (customer_id,line)
+  index orders_email_key (email) unique
+  index orders_pk (customer_id,line) primary unique`,
+		},
+		{
+			name: "quoted identifiers keep their case",
+			sql:  `CREATE TABLE shop."Odd Name" ("ID" int, Plain int);`,
+			want: `shop.Odd Name
+  1 ID integer
+  2 plain integer`,
+		},
+		{
+			name: "types are mapped as by information_schema",
+			sql: `CREATE TABLE shop.kinds (
+				a int2, b int8, c float, d float(10), e double precision, f bool,
+				g char, h timestamp(3) without time zone, i time with time zone, j jsonb, k pg_catalog.int4
+			);`,
+			want: `shop.kinds
+  1 a smallint
+  2 b bigint
+  3 c double precision
+  4 d real
+  5 e double precision
+  6 f boolean
+  7 g character(1)
+  8 h timestamp without time zone
+  9 i time with time zone
+  10 j jsonb
+  11 k integer`,
+		},
+		{
+			name: "unsupported types",
+			sql:  `CREATE TABLE shop.bad (a geometry);`,
+			err:  "unsupported type `geometry` for column `a`",
+		},
+		{
+			name: "array types",
+			sql:  `CREATE TABLE shop.bad (a text[]);`,
+			err:  "unsupported array type `text[]`",
+		},
+		{
+			name: "create index",
+			sql: `CREATE TABLE shop.t (a int, b int, c text);
+				CREATE UNIQUE INDEX t_ab ON shop.t USING btree (a, b DESC);
+				CREATE INDEX ON t (c);
+				CREATE INDEX CONCURRENTLY t_lower ON t (lower(c));
+				CREATE INDEX t_mixed ON t (lower(c), b);`,
+			want: `shop.t
+  1 a integer
+  2 b integer
+  3 c text
+  index t_ab (a,b) unique
+  index t_c_idx (c)
+  index t_mixed (b)`,
+		},
+		{
+			name: "comments",
+			sql: `CREATE TABLE shop.t (a int, v int NOT NULL);
+				COMMENT ON TABLE shop.t IS 'The table';
+				COMMENT ON COLUMN t.a IS 'It''s a column';
+				COMMENT ON COLUMN shop.t.v IS $$ng:version The version$$;
+				COMMENT ON CONSTRAINT x ON shop.t IS 'Ignored';`,
+			want: `shop.t -- The table
+  1 a integer -- It's a column
+  2 v integer NOT NULL version -- The version`,
+		},
+		{
+			name: "drop table",
+			sql: `CREATE TABLE shop.a (id int); CREATE TABLE shop.b (id int);
+				DROP TABLE IF EXISTS shop.a, shop.missing CASCADE;`,
+			want: `shop.b
+  1 id integer`,
+		},
+		{
+			name:    "set search path",
+			schemas: []string{"shop", "other"},
+			sql: `CREATE TABLE a (id int);
+				SET search_path TO other;
+				CREATE TABLE b (id int);`,
+			want: `shop.a
+  1 id integer
+other.b
+  1 id integer`,
+		},
+		{
+			name: "other statements are ignored",
+			sql: `CREATE TEMP TABLE shop.scratch (id int);
+				CREATE FUNCTION shop.f() RETURNS int AS $$ SELECT 1; $$ LANGUAGE sql;
+				CREATE TRIGGER x AFTER INSERT ON shop.a FOR EACH ROW EXECUTE FUNCTION shop.f();
+				CREATE TABLE shop.a (id int);
+				GRANT ALL ON shop.a TO someone;`,
+			want: `shop.a
+  1 id integer`,
+		},
+		{
+			name: "statements about views are skipped",
+			sql: `CREATE TABLE shop.a (id int);
+				CREATE OR REPLACE VIEW shop.v AS SELECT id FROM shop.a;
+				ALTER TABLE shop.v OWNER TO someone;
+				COMMENT ON VIEW shop.v IS 'A view';
+				COMMENT ON COLUMN shop.v.id IS 'A view column';
+				CREATE MATERIALIZED VIEW shop.m AS SELECT id FROM shop.a;
+				CREATE INDEX ON shop.m (id);`,
+			want: `shop.a
+  1 id integer`,
+		},
+		{
+			name: "statements about unknown tables are skipped with a warning",
+			sql: `CREATE TABLE shop.a (id int);
+				ALTER TABLE shop.elsewhere ADD COLUMN x int;
+				ALTER TABLE IF EXISTS shop.quiet ADD COLUMN x int;`,
+			want: `shop.a
+  1 id integer`,
+			log: "Skipping `ALTER TABLE shop.elsewhere ADD COLUMN x ...` as `shop.elsewhere` is not a table created by the SQL",
+		},
+		{
+			name: "tables created twice",
+			sql:  `CREATE TABLE shop.a (id int); CREATE TABLE shop.a (id int);`,
+			err:  "table `shop.a` is created twice",
+		},
+		{
+			name: "create table if not exists leaves an existing table",
+			sql:  `CREATE TABLE shop.a (id int); CREATE TABLE IF NOT EXISTS shop.a (other text);`,
+			want: `shop.a
+  1 id integer`,
+		},
+		{
+			name: "columns added twice",
+			sql:  `CREATE TABLE shop.a (id int); ALTER TABLE shop.a ADD COLUMN id int;`,
+			err:  "column `id` is defined twice",
+		},
+		{
+			name: "add column if not exists leaves an existing column",
+			sql: `CREATE TABLE shop.a (id int);
+				ALTER TABLE shop.a ADD COLUMN IF NOT EXISTS id text CHECK (length(id) > 0), ADD IF NOT EXISTS b int;`,
+			want: `shop.a
+  1 id integer
+  2 b integer`,
+		},
+		{
+			name: "indexes created twice",
+			sql:  `CREATE TABLE shop.a (id int); CREATE INDEX a_i ON shop.a (id); CREATE INDEX a_i ON shop.a (id);`,
+			err:  "index `shop.a_i` is created twice",
+		},
+		{
+			name: "create index if not exists leaves an existing index",
+			sql: `CREATE TABLE shop.a (id int PRIMARY KEY, b int);
+				CREATE INDEX IF NOT EXISTS a_pkey ON shop.a (b);
+				CREATE INDEX IF NOT EXISTS a_b ON shop.a (b);
+				CREATE INDEX IF NOT EXISTS a_b ON shop.a (b);`,
+			want: `shop.a
+  1 id integer NOT NULL
+  2 b integer
+  constraint a_pkey PRIMARY KEY (id)
+  index a_b (b)
+  index a_pkey (id) primary unique`,
+		},
+		{
+			name: "alter table add",
+			sql: `CREATE TABLE shop.a (id int);
+				CREATE TABLE shop.b (id int);
+				ALTER TABLE ONLY shop.a ADD CONSTRAINT a_pk PRIMARY KEY (id);
+				ALTER TABLE shop.b ADD COLUMN a_id int NOT NULL, ADD FOREIGN KEY (a_id) REFERENCES shop.a (id);`,
+			want: `shop.a
+  1 id integer NOT NULL
+  constraint a_pk PRIMARY KEY (id)
+  index a_pk (id) primary unique
+shop.b
+  1 id integer
+  2 a_id integer NOT NULL
+  constraint b_a_id_fkey FOREIGN KEY (a_id) REFERENCES shop.a(id)`,
+		},
+		{
+			name: "drop column removes its constraints and indexes",
+			sql: `CREATE TABLE shop.a (id int PRIMARY KEY, legacy text UNIQUE, name text);
+				CREATE INDEX ON shop.a (legacy, name);
+				ALTER TABLE shop.a DROP COLUMN legacy, DROP IF EXISTS missing;`,
+			want: `shop.a
+  1 id integer NOT NULL
+  3 name text
+  constraint a_pkey PRIMARY KEY (id)
+  index a_pkey (id) primary unique`,
+		},
+		{
+			name: "drop missing column",
+			sql:  `CREATE TABLE shop.a (id int); ALTER TABLE shop.a DROP COLUMN missing;`,
+			err:  "column `missing` does not exist on `shop.a`",
+		},
+		{
+			name: "drop referenced column",
+			sql: `CREATE TABLE shop.a (id int PRIMARY KEY);
+				CREATE TABLE shop.b (a_id int REFERENCES shop.a);
+				ALTER TABLE shop.a DROP COLUMN id;`,
+			err: "column `id` is referenced by foreign key `b_a_id_fkey` on `shop.b` (use CASCADE)",
+		},
+		{
+			name: "drop referenced column with cascade",
+			sql: `CREATE TABLE shop.a (id int PRIMARY KEY, x int);
+				CREATE TABLE shop.b (a_id int REFERENCES shop.a);
+				ALTER TABLE shop.a DROP COLUMN id CASCADE;`,
+			want: `shop.a
+  2 x integer
+shop.b
+  1 a_id integer`,
+		},
+		{
+			name: "drop constraint",
+			sql: `CREATE TABLE shop.a (id int CONSTRAINT a_pk PRIMARY KEY, code text UNIQUE);
+				ALTER TABLE shop.a DROP CONSTRAINT a_code_key, DROP CONSTRAINT IF EXISTS missing;`,
+			want: `shop.a
+  1 id integer NOT NULL
+  2 code text
+  constraint a_pk PRIMARY KEY (id)
+  index a_pk (id) primary unique`,
+		},
+		{
+			name: "drop missing constraint",
+			sql:  `CREATE TABLE shop.a (id int); ALTER TABLE shop.a DROP CONSTRAINT missing;`,
+			err:  "constraint `missing` does not exist on `shop.a`",
+		},
+		{
+			name: "rename column",
+			sql: `CREATE TABLE shop.a (id int PRIMARY KEY, name text);
+				CREATE TABLE shop.b (a_id int REFERENCES shop.a (id));
+				CREATE INDEX ON shop.a (name);
+				ALTER TABLE shop.a RENAME COLUMN name TO title;
+				ALTER TABLE shop.a RENAME id TO a_id;`,
+			want: `shop.a
+  1 a_id integer NOT NULL
+  2 title text
+  constraint a_pkey PRIMARY KEY (a_id)
+  index a_name_idx (title)
+  index a_pkey (a_id) primary unique
+shop.b
+  1 a_id integer
+  constraint b_a_id_fkey FOREIGN KEY (a_id) REFERENCES shop.a(a_id)`,
+		},
+		{
+			name: "rename column to an existing one",
+			sql:  `CREATE TABLE shop.a (a int, b int); ALTER TABLE shop.a RENAME a TO b;`,
+			err:  "column `b` already exists on `shop.a`",
+		},
+		{
+			name: "rename table",
+			sql: `CREATE TABLE shop.a (id int PRIMARY KEY);
+				CREATE TABLE shop.b (a_id int REFERENCES shop.a);
+				ALTER TABLE shop.a RENAME TO c;`,
+			want: `shop.b
+  1 a_id integer
+  constraint b_a_id_fkey FOREIGN KEY (a_id) REFERENCES shop.c(id)
+shop.c
+  1 id integer NOT NULL
+  constraint a_pkey PRIMARY KEY (id)
+  index a_pkey (id) primary unique`,
+		},
+		{
+			name: "rename constraint",
+			sql: `CREATE TABLE shop.a (id int PRIMARY KEY);
+				ALTER TABLE shop.a RENAME CONSTRAINT a_pkey TO a_pk;`,
+			want: `shop.a
+  1 id integer NOT NULL
+  constraint a_pk PRIMARY KEY (id)
+  index a_pk (id) primary unique`,
+		},
+		{
+			name:    "set schema",
+			schemas: []string{"shop", "other"},
+			sql: `CREATE TABLE shop.a (id int PRIMARY KEY);
+				CREATE TABLE shop.b (a_id int REFERENCES shop.a (id));
+				ALTER TABLE shop.a SET SCHEMA other;`,
+			want: `shop.b
+  1 a_id integer
+  constraint b_a_id_fkey FOREIGN KEY (a_id) REFERENCES other.a(id)
+other.a
+  1 id integer NOT NULL
+  constraint a_pkey PRIMARY KEY (id)
+  index a_pkey (id) primary unique`,
+		},
+		{
+			name: "alter column",
+			sql: `CREATE TABLE shop.a (price int, code varchar(5) NOT NULL, notes text DEFAULT '', n int);
+				ALTER TABLE shop.a
+					ALTER COLUMN price TYPE numeric(12, 2) USING price::numeric(12, 2),
+					ALTER price SET NOT NULL,
+					ALTER code SET DATA TYPE varchar(20) COLLATE "C",
+					ALTER code DROP NOT NULL,
+					ALTER notes DROP DEFAULT,
+					ALTER n SET DEFAULT 1 + 2,
+					ALTER n SET STATISTICS 100;`,
+			want: `shop.a
+  1 price numeric(12) NOT NULL
+  2 code character varying(20)
+  3 notes text
+  4 n integer DEFAULT 1 + 2`,
+		},
+		{
+			name: "alter column to serial",
+			sql:  `CREATE TABLE shop.a (id int); ALTER TABLE shop.a ALTER id TYPE serial;`,
+			err:  "column `id` cannot be changed to a serial type",
+		},
+		{
+			name: "alter missing column",
+			sql:  `CREATE TABLE shop.a (id int); ALTER TABLE shop.a ALTER missing SET NOT NULL;`,
+			err:  "column `missing` does not exist on `shop.a`",
+		},
+		{
+			name: "alter table actions not affecting the model",
+			sql: `CREATE TABLE shop.a (id int);
+				ALTER TABLE IF EXISTS shop.a OWNER TO someone, ENABLE TRIGGER ALL, SET (fillfactor = 70);
+				ALTER TABLE shop.a REPLICA IDENTITY FULL;`,
+			want: `shop.a
+  1 id integer`,
+		},
+		{
+			name: "unsupported alter table actions",
+			sql:  `CREATE TABLE shop.a (id int); ALTER TABLE shop.a SET WITH OIDS;`,
+			err:  "unsupported ALTER TABLE action `SET WITH OIDS`",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			schemas := test.schemas
+			if len(schemas) == 0 {
+				schemas = []string{"shop"}
+			}
+			filename := filepath.Join(t.TempDir(), "test.sql")
+			if err := os.WriteFile(filename, []byte(test.sql), 0644); err != nil {
+				t.Fatal(err)
+			}
+			var log bytes.Buffer
+			s, err := New(Options{SchemaNames: schemas, Log: &log})
+			if err != nil {
+				t.Fatal(err)
+			}
+			schema, err := s.ScanSQLFiles([]string{filename})
+			if len(test.err) > 0 {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("expected error containing %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := describeSchema(schema, true); got != test.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, test.want)
+			}
+			if len(test.log) > 0 && !strings.Contains(log.String(), test.log) {
+				t.Errorf("expected the log to contain %q, got:\n%s", test.log, log.String())
+			} else if len(test.log) == 0 && strings.Contains(log.String(), "Skipping") {
+				t.Errorf("unexpected warning in the log:\n%s", log.String())
+			}
+		})
+	}
+}
+
+// TestPostgresSQLRoundTrip compares the tables read from the `postgres.sql`
+// in the repo root with those in a `dump.json` from scanning a database
+// created with it. To refresh the dump, run `postgres.sql` against a
+// database and copy the `dump.json` generated with `-schema example`.
+//
+// Postgres normalises column defaults (eg `NOW()` becomes `now()`) whereas
+// they are kept as written in SQL files, so only their presence is compared.
+// Views aren't read from SQL files, so they are left out.
+func TestPostgresSQLRoundTrip(t *testing.T) {
+	s, err := New(Options{SchemaNames: []string{"example"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromSQL, err := s.ScanSQLFiles([]string{filepath.Join("..", "..", "postgres.sql")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromDatabase, err := LoadDump(filepath.Join("testdata", "example-dump.json"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tables := []model.Table{}
+	for _, table := range fromDatabase.Tables {
+		if table.TableType == "BASE TABLE" {
+			tables = append(tables, table)
+		}
+	}
+	fromDatabase.Tables = tables
+
+	got, want := describeSchema(fromSQL, false), describeSchema(fromDatabase, false)
+	if got != want {
+		t.Errorf("from SQL:\n%s\nfrom the database:\n%s", got, want)
+	}
+}
+
+// describeTokens summarises tokens by kind (word, quoted, value, or symbol) and text.
+func describeTokens(tokens []sqlToken) string {
+	kinds := map[int]string{sqlWord: "w", sqlQuoted: "q", sqlString: "v", sqlSymbol: "s"}
+	parts := []string{}
+	for _, token := range tokens {
+		parts = append(parts, kinds[token.kind]+":"+token.text)
+	}
+	return strings.Join(parts, " ")
+}
+
+// describeSchema summarises the parts of the tables which come from the
+// database, with constraints and indexes in name order. Defaults are only
+// shown if exact, otherwise just their presence is.
+func describeSchema(schema model.Schema, exactDefaults bool) string {
+	lines := []string{}
+	for _, table := range schema.Tables {
+		line := table.SchemaName + "." + table.TableName
+		if len(table.Comment) > 0 {
+			line += " -- " + table.Comment
+		}
+		lines = append(lines, line)
+		for _, col := range table.Columns {
+			line := fmt.Sprintf("  %d %s %s", col.Position, col.ColumnName, col.SqlType)
+			if col.HasMaxLen {
+				line += fmt.Sprintf("(%d)", *col.MaxLen)
+			} else if col.SqlType == "numeric" && col.HasPrecision {
+				line += fmt.Sprintf("(%d)", *col.NumericPrecision)
+			}
+			if !col.IsNullable {
+				line += " NOT NULL"
+			}
+			if col.HasDefault && exactDefaults {
+				line += " DEFAULT " + *col.ColumnDefault
+			} else if col.HasDefault {
+				line += " DEFAULT"
+			}
+			if col.IsVersion {
+				line += " version"
+			}
+			if len(col.Comment) > 0 {
+				line += " -- " + col.Comment
+			}
+			lines = append(lines, line)
+		}
+		constraints := []string{}
+		for _, c := range table.Constraints {
+			line := fmt.Sprintf("  constraint %s %s (%s)", c.ConstraintName, c.ConstraintType, strings.Join(c.ColumnNames, ","))
+			if c.IsForeignKey {
+				line += fmt.Sprintf(" REFERENCES %s.%s(%s)", *c.ForeignSchema, *c.ForeignTable, *c.ForeignColumn)
+			}
+			constraints = append(constraints, line)
+		}
+		sort.Strings(constraints)
+		lines = append(lines, constraints...)
+		indexes := []string{}
+		for _, idx := range table.Indexes {
+			line := fmt.Sprintf("  index %s (%s)", idx.IndexName, strings.Join(idx.ColumnNames, ","))
+			if idx.IsPrimaryKey {
+				line += " primary"
+			}
+			if idx.IsUnique {
+				line += " unique"
+			}
+			indexes = append(indexes, line)
+		}
+		sort.Strings(indexes)
+		lines = append(lines, indexes...)
+	}
+	return strings.Join(lines, "\n")
+}
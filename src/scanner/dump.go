@@ -0,0 +1,40 @@
+package scanner
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/kcartlidge/ng/src/mapping"
+	"github.com/kcartlidge/ng/src/model"
+)
+
+// LoadDump reads a schema previously written to a `dump.json` file.
+// Plural names missing from dumps made by older versions are derived, by the
+// namer if given or with the default rules if not.
+func LoadDump(filename string, namer *mapping.Namer) (model.Schema, error) {
+	if namer == nil {
+		namer = mapping.NewNamer(nil, nil, nil)
+	}
+	schema := model.Schema{}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return schema, err
+	}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return schema, fmt.Errorf("dump %s: %w", filename, err)
+	}
+	if len(schema.SchemaName) == 0 {
+		return schema, fmt.Errorf("dump %s: no schema name found", filename)
+	}
+	for i := range schema.Tables {
+		table := &schema.Tables[i]
+		if len(table.CodeNamePlural) == 0 {
+			table.CodeNamePlural = namer.ToPlural(table.CodeName)
+		}
+		if len(table.JsonNamePlural) == 0 {
+			table.JsonNamePlural = namer.ToPlural(table.JsonName)
+		}
+	}
+	return schema, nil
+}
@@ -0,0 +1,518 @@
+// Package scanner builds a schema model by scanning a Postgres database,
+// reading SQL DDL files, or loading a previous `dump.json` file.
+package scanner
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	pgx "github.com/jackc/pgx/v5/pgxpool"
+	"github.com/kcartlidge/ng/src/mapping"
+	"github.com/kcartlidge/ng/src/model"
+)
+
+var bg = context.Background()
+
+// Conventions are the column names given special treatment in generated code.
+// An empty name switches off that treatment.
+type Conventions struct {
+	SoftDelete string
+	CreatedAt  string
+	UpdatedAt  string
+}
+
+// Filters restrict which tables and views are scanned, using glob patterns (eg `audit_*`).
+// Patterns containing a dot are matched against `schema.table`, others against just the name.
+type Filters struct {
+	Include []string
+	Exclude []string
+}
+
+// Check returns whether the table should be scanned, and if not then why.
+// If there are any includes the table must match one, and it must not match any excludes.
+func (f Filters) Check(schemaName string, tableName string) (bool, string) {
+	if len(f.Include) > 0 {
+		if _, ok := matchesAny(f.Include, schemaName, tableName); !ok {
+			return false, "not included"
+		}
+	}
+	if pattern, ok := matchesAny(f.Exclude, schemaName, tableName); ok {
+		return false, fmt.Sprintf("excluded by `%s`", pattern)
+	}
+	return true, ""
+}
+
+// matchesAny returns the first pattern matching the table, if any.
+func matchesAny(patterns []string, schemaName string, tableName string) (string, bool) {
+	for _, pattern := range patterns {
+		name := tableName
+		if strings.Contains(pattern, ".") {
+			name = schemaName + "." + tableName
+		}
+		if ok, err := path.Match(pattern, name); err == nil && ok {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+// Naming overrides the code names derived for particular tables and columns.
+// Table keys are `table` or `schema.table`, and column keys are `table.column`
+// or `schema.table.column`. The more specific key wins.
+type Naming struct {
+	Tables  map[string]string
+	Columns map[string]string
+}
+
+// Table returns any code name override for the table.
+func (n Naming) Table(schemaName string, tableName string) (string, bool) {
+	return lookupName(n.Tables, schemaName+"."+tableName, tableName)
+}
+
+// Column returns any code name override for the column.
+func (n Naming) Column(schemaName string, tableName string, columnName string) (string, bool) {
+	return lookupName(n.Columns, schemaName+"."+tableName+"."+columnName, tableName+"."+columnName)
+}
+
+// lookupName returns the first non-empty name found for the keys.
+func lookupName(names map[string]string, keys ...string) (string, bool) {
+	for _, key := range keys {
+		if name := strings.TrimSpace(names[key]); len(name) > 0 {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// Options control what is scanned and how it is named.
+// The first schema is the primary one, used for the overall naming.
+// Names are derived by the namer, or with the default rules if there isn't one.
+// Progress is written to the log, if given.
+type Options struct {
+	ConnectionString string
+	SchemaNames      []string
+	Conventions      Conventions
+	Filters          Filters
+	Naming           Naming
+	Namer            *mapping.Namer
+	Log              io.Writer
+}
+
+// Scanner creates a schema model from a database or SQL files.
+type Scanner struct {
+	Schema      model.Schema
+	SchemaNames []string
+	Conventions Conventions
+	Filters     Filters
+	Naming      Naming
+	Namer       *mapping.Namer
+
+	connectionString string
+	log              io.Writer
+}
+
+// New creates a scanner for one or more schemas.
+func New(options Options) (*Scanner, error) {
+	if len(options.SchemaNames) == 0 {
+		return nil, errors.New("no database schema was given")
+	}
+	if options.Namer == nil {
+		options.Namer = mapping.NewNamer(nil, nil, nil)
+	}
+	if options.Log == nil {
+		options.Log = io.Discard
+	}
+	s := &Scanner{
+		Schema:           model.Schema{},
+		SchemaNames:      options.SchemaNames,
+		Conventions:      options.Conventions,
+		Filters:          options.Filters,
+		Naming:           options.Naming,
+		Namer:            options.Namer,
+		connectionString: options.ConnectionString,
+		log:              options.Log,
+	}
+	return s, nil
+}
+
+// ScanPostgresDatabase creates the schema model from the database.
+func (s *Scanner) ScanPostgresDatabase() (model.Schema, error) {
+	db, err := pgxpool.New(bg, s.connectionString)
+	if err != nil {
+		return model.Schema{}, err
+	}
+	defer db.Close()
+	if err := db.Ping(bg); err != nil {
+		return model.Schema{}, err
+	}
+	fmt.Fprintln(s.log, "Connected to Postgres")
+	s.Schema = s.newSchema()
+	for _, schemaName := range s.SchemaNames {
+		fmt.Fprintf(s.log, "Scanning schema `%s`\n", schemaName)
+		if err := s.scanTablesAndViews(db, schemaName); err != nil {
+			return model.Schema{}, err
+		}
+	}
+	s.resolveForeignKeys()
+	return s.Schema, nil
+}
+
+// newSchema creates an empty schema named after the primary (first) one.
+func (s *Scanner) newSchema() model.Schema {
+	primary := s.SchemaNames[0]
+	return model.Schema{
+		SchemaName:  primary,
+		SchemaNames: s.SchemaNames,
+		CodeName:    s.Namer.ToProper(primary, false),
+		DisplayName: s.Namer.ToProper(primary, true),
+		JsonName:    mapping.ToJsonName(primary),
+		SlugName:    mapping.ToSlug(primary),
+		Owner:       primary,
+		Tables:      []model.Table{},
+		Skipped:     []model.SkippedTable{},
+	}
+}
+
+func (s *Scanner) scanTablesAndViews(db *pgx.Pool, schemaName string) error {
+	statement := "SELECT table_name, table_type, is_insertable_into, " +
+		"       pg_catalog.obj_description(pgc.oid, 'pg_class') as table_description " +
+		"FROM   information_schema.tables, pg_catalog.pg_class pgc " +
+		"WHERE  table_schema = $1 " +
+		"AND    table_name = pgc.relname " +
+		"AND    pgc.relnamespace = (SELECT oid FROM pg_catalog.pg_namespace WHERE nspname = table_schema) " +
+		"AND    table_type IN ('BASE TABLE','VIEW') " +
+		"ORDER  BY table_name;"
+	rows, err := db.Query(bg, statement, schemaName)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		tableName, tableType, canInsert, comment := "", "", "", sql.NullString{}
+		if err := rows.Scan(&tableName, &tableType, &canInsert, &comment); err != nil {
+			return err
+		}
+		if tableType == "VIEW" {
+			canInsert = "no"
+		}
+		if !s.isIncluded(schemaName, tableName, tableType) {
+			continue
+		}
+		fmt.Fprintf(s.log, "Scanning %s `%s`\n", strings.ToLower(tableType), tableName)
+		table := s.newTable(schemaName, tableName, tableType, strings.ToLower(canInsert) == "yes", comment.String)
+		if table.Columns, err = s.scanColumns(db, schemaName, tableName, strings.ToUpper(tableType) == "VIEW"); err != nil {
+			return err
+		}
+		if table.Constraints, err = s.scanConstraints(db, schemaName, tableName); err != nil {
+			return err
+		}
+		if table.Indexes, err = s.scanIndexes(db, &table); err != nil {
+			return err
+		}
+		s.addTable(table)
+	}
+	return rows.Err()
+}
+
+// isIncluded checks the table against the filters, recording it as skipped if need be.
+func (s *Scanner) isIncluded(schemaName string, tableName string, tableType string) bool {
+	ok, reason := s.Filters.Check(schemaName, tableName)
+	if !ok {
+		fmt.Fprintf(s.log, "Skipping %s `%s` (%s)\n", strings.ToLower(tableType), tableName, reason)
+		s.Schema.Skipped = append(s.Schema.Skipped, model.SkippedTable{
+			SchemaName: schemaName,
+			TableName:  tableName,
+			TableType:  tableType,
+			Reason:     reason,
+		})
+	}
+	return ok
+}
+
+// newTable creates a table (without columns etc) with names derived from the table name.
+func (s *Scanner) newTable(schemaName string, tableName string, tableType string, isUpdatable bool, comment string) model.Table {
+	// With several schemas, names are prefixed by the schema to keep them unique.
+	name := tableName
+	if len(s.SchemaNames) > 1 {
+		name = schemaName + "_" + tableName
+	}
+	table := model.Table{
+		SchemaName:        schemaName,
+		TableName:         tableName,
+		CodeName:          s.Namer.ToProper(name, false),
+		CodeNamePlural:    s.Namer.ToPlural(s.Namer.ToProper(name, false)),
+		DisplayName:       s.Namer.ToProper(name, true),
+		DisplayNamePlural: s.Namer.ToPlural(s.Namer.ToProper(name, true)),
+		JsonName:          mapping.ToJsonName(name),
+		JsonNamePlural:    s.Namer.ToPlural(mapping.ToJsonName(name)),
+		SlugName:          mapping.ToSlug(name),
+		SlugNamePlural:    s.Namer.ToPlural(mapping.ToSlug(name)),
+		Owner:             schemaName,
+		Comment:           strings.TrimSpace(strings.ReplaceAll(comment, "?", "")),
+		TableType:         tableType,
+		IsUpdatable:       isUpdatable,
+		Columns:           []model.Column{},
+		Constraints:       []model.Constraint{},
+		Indexes:           []model.Index{},
+		CodeImports:       []string{},
+	}
+	if codeName, ok := s.Naming.Table(schemaName, tableName); ok {
+		table.CodeName = codeName
+		table.CodeNamePlural = s.Namer.ToPlural(codeName)
+	}
+	return table
+}
+
+// addTable applies the column conventions and adds the completed table to the schema.
+func (s *Scanner) addTable(table model.Table) {
+	mapping.MarkVersionColumn(&table)
+	mapping.MarkSoftDeleteColumn(&table, s.Conventions.SoftDelete)
+	mapping.MarkAuditColumns(&table, s.Conventions.CreatedAt, s.Conventions.UpdatedAt)
+	needsTime := false
+	for _, col := range table.Columns {
+		if col.CanFilter {
+			switch col.DataType {
+			case "*time.Time":
+				needsTime = true
+			}
+		}
+	}
+	if needsTime {
+		s.addCodeImport(&table, "time")
+	}
+	s.Schema.Tables = append(s.Schema.Tables, table)
+}
+
+func (s *Scanner) addCodeImport(table *model.Table, requires string) {
+	for i := range table.CodeImports {
+		if table.CodeImports[i] == requires {
+			return
+		}
+	}
+	table.CodeImports = append(table.CodeImports, requires)
+}
+
+func (s *Scanner) scanColumns(db *pgx.Pool, schemaName string, tableName string, isView bool) ([]model.Column, error) {
+	result := []model.Column{}
+	statement := "SELECT ordinal_position, column_name, is_nullable, data_type, character_maximum_length, column_default, numeric_precision, " +
+		"       pg_catalog.col_description(format('%s.%s',table_schema,table_name)::regclass::oid,ordinal_position) as column_description " +
+		"FROM   information_schema.columns " +
+		"WHERE  table_schema = $1 " +
+		"AND    table_name = $2"
+	rows, err := db.Query(bg, statement, schemaName, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		position, name, nullable, dataType, comment := 0, "", "", "", sql.NullString{}
+		var maxLen *int
+		var columnDefault *string
+		var numericPrecision *int
+		if err := rows.Scan(&position, &name, &nullable, &dataType, &maxLen, &columnDefault, &numericPrecision, &comment); err != nil {
+			return nil, err
+		}
+		col, err := s.newColumn(schemaName, tableName, isView, position, name, dataType, strings.ToLower(nullable) == "yes", comment.String)
+		if err != nil {
+			return nil, err
+		}
+		col.HasMaxLen, col.MaxLen = maxLen != nil, maxLen
+		col.HasDefault, col.ColumnDefault = columnDefault != nil, columnDefault
+		col.HasPrecision, col.NumericPrecision = numericPrecision != nil, numericPrecision
+		result = append(result, col)
+	}
+	return result, rows.Err()
+}
+
+// newColumn creates a column with names derived from the column name.
+// An `ng:version` directive in the comment marks it for optimistic concurrency.
+func (s *Scanner) newColumn(schemaName string, tableName string, isView bool, position int, name string, dataType string, isNullable bool, comment string) (model.Column, error) {
+	goType, err := mapping.PostgresTypeToGoWithNullable(dataType, isNullable)
+	if err != nil {
+		return model.Column{}, fmt.Errorf("%s.%s.%s: %w", schemaName, tableName, name, err)
+	}
+	columnComment, isVersion := mapping.TakeDirective(strings.TrimSpace(comment), "version")
+	col := model.Column{
+		Position:    position,
+		ColumnName:  name,
+		CodeName:    s.Namer.ToProper(name, false),
+		DisplayName: s.Namer.ToProper(name, true),
+		JsonName:    mapping.ToJsonName(name),
+		SlugName:    mapping.ToSlug(name),
+		Comment:     columnComment,
+		IsNullable:  isNullable,
+		IsCardinal:  mapping.IsPostgresTypeCardinal(dataType),
+		CanFilter:   isView,
+		IsVersion:   isVersion && !isView,
+		SqlType:     dataType,
+		DataType:    goType,
+	}
+	if codeName, ok := s.Naming.Column(schemaName, tableName, name); ok {
+		col.CodeName = codeName
+	}
+	return col, nil
+}
+
+func (s *Scanner) scanConstraints(db *pgx.Pool, schemaName string, tableName string) ([]model.Constraint, error) {
+	result := []model.Constraint{}
+	columnAdded := make(map[string]int)
+	statement := "SELECT tc.constraint_name, kc.column_name, tc.constraint_type, " +
+		"       cc.table_schema as ref_schema, cc.table_name as ref_table, cc.column_name as ref_column " +
+		"FROM   information_schema.table_constraints tc, information_schema.key_column_usage kc, " +
+		"       information_schema.constraint_column_usage cc " +
+		"WHERE  kc.table_name = tc.table_name " +
+		"AND    kc.table_schema = tc.table_schema " +
+		"AND    kc.constraint_name = tc.constraint_name " +
+		"AND    cc.constraint_name = tc.constraint_name " +
+		"AND    cc.constraint_schema = tc.constraint_schema " +
+		"AND    kc.table_schema = $1 " +
+		"AND    kc.table_name = $2"
+	rows, err := db.Query(bg, statement, schemaName, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		name, columnName, constraintType, refSchema, refTable, refColumn := "", "", "", "", "", ""
+		if err := rows.Scan(&name, &columnName, &constraintType, &refSchema, &refTable, &refColumn); err != nil {
+			return nil, err
+		}
+		if i, ok := columnAdded[name]; ok {
+			result[i].ColumnNames = append(result[i].ColumnNames, columnName)
+		} else {
+			constraint := newConstraint(s.Namer, name, constraintType, []string{columnName})
+			if constraint.IsForeignKey {
+				constraint.ForeignSchema = &refSchema
+				constraint.ForeignTable = &refTable
+				constraint.ForeignColumn = &refColumn
+			}
+			result = append(result, constraint)
+			columnAdded[name] = len(result) - 1
+		}
+	}
+	return result, rows.Err()
+}
+
+func (s *Scanner) scanIndexes(db *pgx.Pool, table *model.Table) ([]model.Index, error) {
+	result := []model.Index{}
+	statement := "SELECT relname, indkey, indisprimary, indisunique " +
+		"FROM   pg_class pc, pg_index pi, pg_indexes ps " +
+		"WHERE  ps.indexname = relname AND ps.schemaname = $1 AND ps.tablename = $2 " +
+		"AND    pc.oid = pi.indexrelid AND pc.relkind = 'i' AND pc.oid IN ( " +
+		"  SELECT indexrelid " +
+		"  FROM   pg_index pi2, pg_class pc2 " +
+		"  WHERE  pc2.relname = $2 " +
+		"  AND    pc2.relnamespace = (SELECT oid FROM pg_catalog.pg_namespace WHERE nspname = $1) " +
+		"  AND    pc2.oid = pi2.indrelid " +
+		"); "
+	rows, err := db.Query(bg, statement, table.SchemaName, table.TableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		name, indkey, isPrimary, isUnique := "", "", false, false
+		if err := rows.Scan(&name, &indkey, &isPrimary, &isUnique); err != nil {
+			return nil, err
+		}
+
+		idx := newIndex(s.Namer, name, isPrimary, isUnique, []string{})
+		for _, s := range strings.Split(indkey, " ") {
+			position, _ := strconv.Atoi(s)
+			for _, c := range table.Columns {
+				if c.Position == position {
+					idx.ColumnNames = append(idx.ColumnNames, c.ColumnName)
+				}
+			}
+		}
+		if len(idx.ColumnNames) == 0 {
+			// Expression indexes have no plain columns.
+			continue
+		}
+		markIndexedColumn(table, idx)
+		result = append(result, idx)
+	}
+	return result, rows.Err()
+}
+
+// newConstraint creates a constraint with names derived from the constraint name.
+func newConstraint(namer *mapping.Namer, name string, constraintType string, columnNames []string) model.Constraint {
+	return model.Constraint{
+		ConstraintName: name,
+		CodeName:       namer.ToProper(name, false),
+		DisplayName:    namer.ToProper(name, true),
+		JsonName:       mapping.ToJsonName(name),
+		SlugName:       mapping.ToSlug(name),
+		IsPrimaryKey:   strings.ToLower(constraintType) == "primary key",
+		IsForeignKey:   strings.ToLower(constraintType) == "foreign key",
+		IsUniqueKey:    strings.ToLower(constraintType) == "unique",
+		ColumnNames:    columnNames,
+		ConstraintType: constraintType,
+		ForeignTable:   nil,
+		ForeignColumn:  nil,
+	}
+}
+
+// newIndex creates an index with names derived from the index name.
+func newIndex(namer *mapping.Namer, name string, isPrimary bool, isUnique bool, columnNames []string) model.Index {
+	return model.Index{
+		IndexName:    name,
+		CodeName:     namer.ToProper(name, false),
+		DisplayName:  namer.ToProper(name, true),
+		JsonName:     mapping.ToJsonName(name),
+		SlugName:     mapping.ToSlug(name),
+		ColumnNames:  columnNames,
+		IsPrimaryKey: isPrimary,
+		IsUnique:     isUnique,
+	}
+}
+
+// markIndexedColumn flags the index's leading column as filterable, and
+// as the primary key if the index is for one.
+func markIndexedColumn(table *model.Table, idx model.Index) {
+	for i := range table.Columns {
+		if table.Columns[i].ColumnName == idx.ColumnNames[0] {
+			table.Columns[i].IsPrimaryKey = table.Columns[i].IsPrimaryKey || idx.IsPrimaryKey
+			table.Columns[i].CanFilter = true
+		}
+	}
+}
+
+// resolveForeignKeys links foreign keys to the scanned tables they reference,
+// which may be in another schema. References to tables which were skipped or
+// not scanned are reported, and left unresolved.
+func (s *Scanner) resolveForeignKeys() {
+	schema := &s.Schema
+	codeNames := make(map[string]string)
+	for _, t := range schema.Tables {
+		codeNames[t.SchemaName+"."+t.TableName] = t.CodeName
+	}
+	skipped := make(map[string]bool)
+	for _, t := range schema.Skipped {
+		skipped[t.SchemaName+"."+t.TableName] = true
+	}
+	for i := range schema.Tables {
+		t := &schema.Tables[i]
+		for j := range t.Constraints {
+			c := &t.Constraints[j]
+			if !c.IsForeignKey || c.ForeignSchema == nil || c.ForeignTable == nil {
+				continue
+			}
+			target := *c.ForeignSchema + "." + *c.ForeignTable
+			if codeName, ok := codeNames[target]; ok {
+				c.ForeignCodeName = &codeName
+			} else if skipped[target] {
+				fmt.Fprintf(s.log, "Foreign key `%s` on `%s.%s` references skipped `%s`\n", c.ConstraintName, t.SchemaName, t.TableName, target)
+			} else {
+				fmt.Fprintf(s.log, "Foreign key `%s` on `%s.%s` references unscanned `%s`\n", c.ConstraintName, t.SchemaName, t.TableName, target)
+			}
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package writer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ManifestFilename lists the files generated into the repo folder, so the
+// next run knows which ones it can remove without touching any others.
+const ManifestFilename = "ng-manifest.json"
+
+type manifest struct {
+	Generator string   `json:"generator"`
+	Files     []string `json:"files"`
+}
+
+// Kinds of change to a file.
+const (
+	fileUnchanged = iota
+	fileCreated
+	fileChanged
+	fileRemoved
+)
+
+// fileChange is what writing would do to a file.
+type fileChange struct {
+	filename string
+	name     string
+	kind     int
+	existing []byte
+	content  []byte
+}
+
+// createManifest adds the manifest of the other generated files.
+func (w *Writer) createManifest() error {
+	m := manifest{Generator: "ng", Files: []string{}}
+	for _, f := range w.files {
+		m.Files = append(m.Files, w.relativeName(f.filename))
+	}
+	sort.Strings(m.Files)
+	b, err := json.MarshalIndent(m, "", "\t")
+	if err != nil {
+		return err
+	}
+	filename := path.Join(w.repoFolder, ManifestFilename)
+	w.files = append(w.files, generatedFile{filename: filename, content: append(b, '\n')})
+	return nil
+}
+
+// previousFiles returns the files listed in the existing manifest, if any.
+func (w *Writer) previousFiles() ([]string, bool, error) {
+	data, err := os.ReadFile(path.Join(w.repoFolder, ManifestFilename))
+	if os.IsNotExist(err) {
+		return []string{}, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	m := manifest{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, false, fmt.Errorf("%s: %w", ManifestFilename, err)
+	}
+	result := []string{}
+	for _, name := range m.Files {
+		// Only files within the repo folder are ever removed.
+		filename := path.Join(w.repoFolder, name)
+		if strings.HasPrefix(filename, w.repoFolder+"/") {
+			result = append(result, filename)
+		}
+	}
+	return append(result, path.Join(w.repoFolder, ManifestFilename)), true, nil
+}
+
+// planChanges compares the generated files with those on disk. Files from
+// the previous manifest which are no longer generated are to be removed.
+// If not exact, the parts of files which vary by where `ng` is run are ignored.
+func (w *Writer) planChanges(exact bool) ([]fileChange, error) {
+	changes := []fileChange{}
+	generated := make(map[string]bool)
+	for _, f := range w.files {
+		generated[f.filename] = true
+		change := fileChange{filename: f.filename, name: w.relativeName(f.filename), kind: fileUnchanged, content: f.content}
+		existing, err := os.ReadFile(f.filename)
+		if os.IsNotExist(err) {
+			change.kind = fileCreated
+		} else if err != nil {
+			return nil, err
+		} else {
+			change.existing = existing
+			if exact && !bytes.Equal(existing, f.content) {
+				change.kind = fileChanged
+			}
+			if !exact && !bytes.Equal(comparable(f.filename, existing), comparable(f.filename, f.content)) {
+				change.kind = fileChanged
+			}
+		}
+		changes = append(changes, change)
+	}
+	previous, _, err := w.previousFiles()
+	if err != nil {
+		return nil, err
+	}
+	for _, filename := range previous {
+		if generated[filename] {
+			continue
+		}
+		existing, err := os.ReadFile(filename)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, fileChange{filename: filename, name: w.relativeName(filename), kind: fileRemoved, existing: existing})
+	}
+	return changes, nil
+}
+
+// compareFiles compares the generated files with those in the output folder.
+// It displays a summary of the differences and returns true if there are any.
+func (w *Writer) compareFiles() (bool, error) {
+	fmt.Fprintln(w.log, "Comparing with", w.repoFolder)
+	fmt.Fprintln(w.log)
+	changes, err := w.planChanges(false)
+	if err != nil {
+		return false, err
+	}
+	differences := w.showChanges(changes, false)
+	if differences == 0 {
+		fmt.Fprintln(w.log, "  Generated code is up to date")
+	} else {
+		fmt.Fprintln(w.log)
+		fmt.Fprintf(w.log, "  %d file(s) differ\n", differences)
+	}
+	return differences > 0, nil
+}
+
+// showChanges lists the files which would be created, changed, or removed,
+// optionally with unified diffs of the changes. It returns how many there are.
+func (w *Writer) showChanges(changes []fileChange, withDiffs bool) int {
+	count := 0
+	for _, c := range changes {
+		oldLines, newLines := splitLines(comparable(c.filename, c.existing)), splitLines(comparable(c.filename, c.content))
+		switch c.kind {
+		case fileCreated:
+			fmt.Fprintf(w.log, "  + %s (new, %d lines)\n", c.name, len(newLines))
+		case fileChanged:
+			if added, removed, ok := countLineChanges(oldLines, newLines); ok {
+				fmt.Fprintf(w.log, "  ~ %s (+%d -%d lines)\n", c.name, added, removed)
+			} else {
+				fmt.Fprintf(w.log, "  ~ %s (changed, over %d lines differ)\n", c.name, maxCountedEdits)
+			}
+		case fileRemoved:
+			fmt.Fprintf(w.log, "  - %s (no longer generated)\n", c.name)
+		default:
+			continue
+		}
+		count++
+		if withDiffs && c.kind == fileChanged {
+			fmt.Fprintln(w.log)
+			if diff, ok := unifiedDiff(c.name, oldLines, newLines); ok {
+				fmt.Fprint(w.log, diff)
+			} else {
+				fmt.Fprintln(w.log, "    File changed, but with too many differences to show")
+			}
+			fmt.Fprintln(w.log)
+		}
+	}
+	return count
+}
+
+// relativeName returns the filename relative to the output folder.
+func (w *Writer) relativeName(filename string) string {
+	if rel, err := filepath.Rel(w.repoFolder, filename); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return filename
+}
+
+// comparable returns the content to compare. The `Regenerating` section of the
+// README is left out, as it records where and how `ng` was last run.
+func comparable(filename string, content []byte) []byte {
+	if path.Base(filename) != "README.md" {
+		return content
+	}
+	lines, keep := []string{}, true
+	for _, line := range splitLines(content) {
+		if strings.HasPrefix(line, "## ") {
+			keep = line != "## Regenerating"
+		}
+		if keep {
+			lines = append(lines, line)
+		}
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
+
+func splitLines(content []byte) []string {
+	if len(content) == 0 {
+		return []string{}
+	}
+	return strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n")
+}
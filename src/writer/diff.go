@@ -0,0 +1,252 @@
+package writer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Line diff operations.
+const (
+	diffSame = iota
+	diffAdd
+	diffRemove
+)
+
+type diffLine struct {
+	op   int
+	text string
+}
+
+// The cost of comparing files grows with the number of differences, so the
+// work is limited. Beyond these edits a file is only reported as changed.
+const (
+	maxCountedEdits = 10000
+	maxDiffEdits    = 1000
+)
+
+// countLineChanges returns how many lines would be added and removed to turn
+// the old lines into the new ones, or false if there are too many to count.
+func countLineChanges(old []string, new []string) (int, int, bool) {
+	a, b, _, _ := trimCommon(old, new)
+	edits, _, ok := shortestEdit(lineIDs(a, b), len(a), maxCountedEdits, false)
+	if !ok {
+		return 0, 0, false
+	}
+	// Each edit is an addition or a removal, and between them they account
+	// for the difference in length.
+	return (edits + len(b) - len(a)) / 2, (edits + len(a) - len(b)) / 2, true
+}
+
+// diffLines returns the edits turning the old lines into the new ones,
+// or false if there are too many to show.
+func diffLines(old []string, new []string) ([]diffLine, bool) {
+	a, b, prefix, suffix := trimCommon(old, new)
+	ids := lineIDs(a, b)
+	edits, trace, ok := shortestEdit(ids, len(a), maxDiffEdits, true)
+	if !ok {
+		return nil, false
+	}
+
+	// Walk back from the end, finding the diagonal each edit came from.
+	// The lines between edits are common to both.
+	middle := []diffLine{}
+	x, y := len(a), len(b)
+	for d := edits; d > 0; d-- {
+		k := x - y
+		previous := trace[d-1]
+		at := func(k int) int { return int(previous[k+d-1]) }
+		prevK := k - 1
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x, y = x-1, y-1
+			middle = append(middle, diffLine{op: diffSame, text: a[x]})
+		}
+		if x == prevX {
+			middle = append(middle, diffLine{op: diffAdd, text: b[prevY]})
+		} else {
+			middle = append(middle, diffLine{op: diffRemove, text: a[prevX]})
+		}
+		x, y = prevX, prevY
+	}
+	for x > 0 {
+		x--
+		middle = append(middle, diffLine{op: diffSame, text: a[x]})
+	}
+
+	result := make([]diffLine, 0, prefix+len(middle)+suffix)
+	for _, line := range old[:prefix] {
+		result = append(result, diffLine{op: diffSame, text: line})
+	}
+	for i := len(middle) - 1; i >= 0; i-- {
+		result = append(result, middle[i])
+	}
+	for _, line := range old[len(old)-suffix:] {
+		result = append(result, diffLine{op: diffSame, text: line})
+	}
+	return result, true
+}
+
+// trimCommon returns the old and new lines without any common start and end,
+// which is usually most of the file, along with the lengths of those.
+func trimCommon(old []string, new []string) ([]string, []string, int, int) {
+	prefix := 0
+	for prefix < len(old) && prefix < len(new) && old[prefix] == new[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(old)-prefix && suffix < len(new)-prefix && old[len(old)-1-suffix] == new[len(new)-1-suffix] {
+		suffix++
+	}
+	return old[prefix : len(old)-suffix], new[prefix : len(new)-suffix], prefix, suffix
+}
+
+// lineIDs numbers the distinct lines, so they can be compared as integers.
+// The old lines come first, followed by the new ones.
+func lineIDs(a []string, b []string) []int {
+	seen := make(map[string]int)
+	ids := make([]int, 0, len(a)+len(b))
+	for _, lines := range [][]string{a, b} {
+		for _, line := range lines {
+			id, ok := seen[line]
+			if !ok {
+				id = len(seen)
+				seen[line] = id
+			}
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// shortestEdit finds the fewest additions and removals turning the first n
+// lines into the rest, using Myers' greedy algorithm in space linear in the
+// lines, or false if more than the limit are needed. If a trace is wanted, it
+// has the furthest point reached on each diagonal after each number of edits.
+func shortestEdit(ids []int, n int, limit int, withTrace bool) (int, [][]int32, bool) {
+	a, b := ids[:n], ids[n:]
+	if limit > len(a)+len(b) {
+		limit = len(a) + len(b)
+	}
+	// v[offset+k] is the furthest x reached on diagonal k (where k = x - y).
+	offset := limit + 1
+	v := make([]int, 2*offset+1)
+	trace := [][]int32{}
+	for d := 0; d <= limit; d++ {
+		for k := -d; k <= d; k += 2 {
+			x := v[offset+k-1] + 1
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			}
+			y := x - k
+			for x < len(a) && y < len(b) && a[x] == b[y] {
+				x, y = x+1, y+1
+			}
+			v[offset+k] = x
+			if x >= len(a) && y >= len(b) {
+				return d, trace, true
+			}
+		}
+		if withTrace {
+			reached := make([]int32, 2*d+1)
+			for i := range reached {
+				reached[i] = int32(v[offset-d+i])
+			}
+			trace = append(trace, reached)
+		}
+	}
+	return 0, nil, false
+}
+
+// unifiedDiff returns the changes between the old and new lines in unified
+// diff format, with three lines of context around each change, or false if
+// there are too many changes to show.
+func unifiedDiff(name string, old []string, new []string) (string, bool) {
+	const context = 3
+	lines, ok := diffLines(old, new)
+	if !ok {
+		return "", false
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("--- a/%s\n+++ b/%s\n", name, name))
+
+	// Old and new line numbers (zero-based) at the start of each diff line.
+	oldAt, newAt := make([]int, len(lines)+1), make([]int, len(lines)+1)
+	for k, d := range lines {
+		oldAt[k+1], newAt[k+1] = oldAt[k], newAt[k]
+		if d.op != diffAdd {
+			oldAt[k+1]++
+		}
+		if d.op != diffRemove {
+			newAt[k+1]++
+		}
+	}
+
+	for k := 0; k < len(lines); {
+		if lines[k].op == diffSame {
+			k++
+			continue
+		}
+		// Extend the hunk while changes are close enough to share context.
+		start, end := max0(k-context), k
+		for end < len(lines) {
+			if lines[end].op != diffSame {
+				end++
+				continue
+			}
+			next := end
+			for next < len(lines) && lines[next].op == diffSame {
+				next++
+			}
+			if next < len(lines) && next-end <= 2*context {
+				end = next
+				continue
+			}
+			end = minInt(end+context, len(lines))
+			break
+		}
+		sb.WriteString(fmt.Sprintf("@@ -%s +%s @@\n",
+			hunkRange(oldAt[start], oldAt[end]-oldAt[start]),
+			hunkRange(newAt[start], newAt[end]-newAt[start])))
+		for _, d := range lines[start:end] {
+			prefix := " "
+			switch d.op {
+			case diffAdd:
+				prefix = "+"
+			case diffRemove:
+				prefix = "-"
+			}
+			sb.WriteString(prefix + d.text + "\n")
+		}
+		k = end
+	}
+	return sb.String(), true
+}
+
+// hunkRange formats a unified diff range, which is one-based unless empty.
+func hunkRange(start int, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+func max0(n int) int {
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,187 @@
+package writer
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name    string
+		old     string
+		new     string
+		added   int
+		removed int
+		diff    string
+		tooMany bool
+	}{
+		{
+			name: "identical",
+			old:  "a\nb\nc\n",
+			new:  "a\nb\nc\n",
+			diff: "",
+		},
+		{
+			name:  "insertion",
+			old:   "a\nb\nc\n",
+			new:   "a\nb\nx\ny\nc\n",
+			added: 2,
+			diff:  "@@ -1,4 +1,6 @@\n a\n b\n+x\n+y\n c\n \n",
+		},
+		{
+			name:    "deletion",
+			old:     "a\nb\nc\nd\n",
+			new:     "a\nd\n",
+			removed: 2,
+			diff:    "@@ -1,5 +1,3 @@\n a\n-b\n-c\n d\n \n",
+		},
+		{
+			name:  "into an empty file",
+			old:   "",
+			new:   "a\nb",
+			added: 2,
+			diff: `@@ -0,0 +1,2 @@
++a
++b
+`,
+		},
+		{
+			name:  "missing trailing newline",
+			old:   "a\nb",
+			new:   "a\nb\n",
+			added: 1,
+			diff: `@@ -1,2 +1,3 @@
+ a
+ b
++
+`,
+		},
+		{
+			name:    "change",
+			old:     lines(1, 10),
+			new:     strings.Replace(lines(1, 10), "line 5\n", "five\n", 1),
+			added:   1,
+			removed: 1,
+			diff: `@@ -2,7 +2,7 @@
+ line 2
+ line 3
+ line 4
+-line 5
++five
+ line 6
+ line 7
+ line 8
+`,
+		},
+		{
+			name:    "hunks merge when their context overlaps",
+			old:     lines(1, 20),
+			new:     strings.NewReplacer("line 5\n", "five\n", "line 12\n", "twelve\n").Replace(lines(1, 20)),
+			added:   2,
+			removed: 2,
+			diff: `@@ -2,14 +2,14 @@
+ line 2
+ line 3
+ line 4
+-line 5
++five
+ line 6
+ line 7
+ line 8
+ line 9
+ line 10
+ line 11
+-line 12
++twelve
+ line 13
+ line 14
+ line 15
+`,
+		},
+		{
+			name:    "hunks are separate when their context doesn't overlap",
+			old:     lines(1, 20),
+			new:     strings.NewReplacer("line 5\n", "five\n", "line 13\n", "thirteen\n").Replace(lines(1, 20)),
+			added:   2,
+			removed: 2,
+			diff: `@@ -2,7 +2,7 @@
+ line 2
+ line 3
+ line 4
+-line 5
++five
+ line 6
+ line 7
+ line 8
+@@ -10,7 +10,7 @@
+ line 10
+ line 11
+ line 12
+-line 13
++thirteen
+ line 14
+ line 15
+ line 16
+`,
+		},
+		{
+			name:    "too many changes to show",
+			old:     lines(1, 600),
+			new:     lines(1001, 1600),
+			added:   600,
+			removed: 600,
+			tooMany: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			old, new := splitLines([]byte(test.old)), splitLines([]byte(test.new))
+			added, removed, ok := countLineChanges(old, new)
+			if !ok || added != test.added || removed != test.removed {
+				t.Errorf("counted +%d -%d (%v), want +%d -%d", added, removed, ok, test.added, test.removed)
+			}
+			diff, ok := unifiedDiff("f", old, new)
+			if test.tooMany {
+				if ok {
+					t.Errorf("expected too many changes to show, got\n%s", diff)
+				}
+				return
+			}
+			if !ok {
+				t.Fatal("expected a diff")
+			}
+			want := "--- a/f\n+++ b/f\n" + test.diff
+			if diff != want {
+				t.Errorf("got\n%s\nwant\n%s", diff, want)
+			}
+		})
+	}
+}
+
+func TestCountLineChangesLimit(t *testing.T) {
+	old, new := splitLines([]byte(lines(1, 6000))), splitLines([]byte(lines(10001, 16000)))
+	if _, _, ok := countLineChanges(old, new); ok {
+		t.Errorf("expected more than %d changes to be too many to count", maxCountedEdits)
+	}
+
+	// Large files with a few changes are still counted and shown.
+	old = splitLines([]byte(lines(1, 200000)))
+	new = append([]string{"first"}, old[1:]...)
+	new[len(new)-2] = "last"
+	if added, removed, ok := countLineChanges(old, new); !ok || added != 2 || removed != 2 {
+		t.Errorf("counted +%d -%d (%v), want +2 -2", added, removed, ok)
+	}
+	if _, ok := unifiedDiff("f", old, new); !ok {
+		t.Error("expected a diff")
+	}
+}
+
+// lines returns numbered lines, each ending with a newline.
+func lines(from int, to int) string {
+	var sb strings.Builder
+	for i := from; i <= to; i++ {
+		fmt.Fprintf(&sb, "line %d\n", i)
+	}
+	return sb.String()
+}
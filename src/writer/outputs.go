@@ -0,0 +1,100 @@
+package writer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OutputsFilename is the manifest of files generated from templates. The
+// built-in one is embedded with the templates, and one in the templates
+// folder adds to (or replaces) its outputs.
+const OutputsFilename = "outputs.yaml"
+
+// Output is a file generated from a template. The path is relative to the
+// repo folder and is itself a template (eg `handlers/{{ .SlugName }}.go`),
+// given the table or the schema depending upon the scope.
+type Output struct {
+	Template string `yaml:"template" json:"template"`
+	Scope    string `yaml:"scope" json:"scope"`
+	Path     string `yaml:"path" json:"path"`
+}
+
+// Output scopes.
+const (
+	ScopeSchema = "schema"
+	ScopeTable  = "table"
+)
+
+// Validate checks the output has a template, a known scope, and a path.
+func (o Output) Validate() error {
+	if len(o.Template) == 0 {
+		return fmt.Errorf("output %q has no template", o.Path)
+	}
+	if len(o.Path) == 0 {
+		return fmt.Errorf("output %q has no path", o.Template)
+	}
+	if o.Scope != ScopeSchema && o.Scope != ScopeTable {
+		return fmt.Errorf("output %q has scope %q (expected %s or %s)", o.Template, o.Scope, ScopeSchema, ScopeTable)
+	}
+	return nil
+}
+
+// LoadOutputs returns the built-in outputs plus any from the templates folder's
+// manifest and the config, in that order. An output for the same template as
+// a built-in one replaces it (eg to change its path).
+func LoadOutputs(templatesFolder string, configured []Output) ([]Output, error) {
+	data, err := fsServer.ReadFile("templates/" + OutputsFilename)
+	if err != nil {
+		return nil, err
+	}
+	outputs, err := parseOutputs(data, OutputsFilename)
+	if err != nil {
+		return nil, err
+	}
+	builtIn := make(map[string]int)
+	for i, o := range outputs {
+		builtIn[o.Template] = i
+	}
+
+	extra := []Output{}
+	if len(templatesFolder) > 0 {
+		filename := filepath.Join(templatesFolder, OutputsFilename)
+		data, err := os.ReadFile(filename)
+		if err == nil {
+			loaded, err := parseOutputs(data, filename)
+			if err != nil {
+				return nil, err
+			}
+			extra = append(extra, loaded...)
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	extra = append(extra, configured...)
+
+	for _, o := range extra {
+		if i, found := builtIn[o.Template]; found {
+			outputs[i] = o
+		} else {
+			outputs = append(outputs, o)
+		}
+	}
+	return outputs, nil
+}
+
+// parseOutputs reads and validates a YAML (or JSON) list of outputs.
+func parseOutputs(data []byte, source string) ([]Output, error) {
+	outputs := []Output{}
+	if err := yaml.Unmarshal(data, &outputs); err != nil {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+	for _, o := range outputs {
+		if err := o.Validate(); err != nil {
+			return nil, fmt.Errorf("%s: %w", source, err)
+		}
+	}
+	return outputs, nil
+}
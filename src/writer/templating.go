@@ -0,0 +1,276 @@
+package writer
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	"github.com/kcartlidge/ng/src/mapping"
+	"github.com/kcartlidge/ng/src/model"
+)
+
+var (
+	//go:embed templates/*.tmpl templates/outputs.yaml
+	fsServer embed.FS
+)
+
+func (w *Writer) getTemplatedData(data interface{}, templateName string) ([]byte, error) {
+	t, err := w.templates()
+	if err != nil {
+		return nil, err
+	}
+	var wr bytes.Buffer
+	if err := t.ExecuteTemplate(&wr, templateName, data); err != nil {
+		return nil, err
+	}
+	return wr.Bytes(), nil
+}
+
+// templates returns the parsed templates, loading them on first use.
+// Any in the templates folder override the embedded ones.
+func (w *Writer) templates() (*template.Template, error) {
+	if w.cache == nil {
+		tfs, err := fs.Sub(fsServer, "templates")
+		if err != nil {
+			return nil, err
+		}
+
+		t, err := template.New("ng").Funcs(template.FuncMap{
+			"lower":  strings.ToLower,
+			"upper":  strings.ToUpper,
+			"plural": w.namer.ToPlural,
+			"quote":  mapping.QuoteIdentifier,
+			"now":    time.Now,
+			"year": func() int {
+				return time.Now().Year()
+			},
+			"sortDate": func(dtm time.Time) string {
+				return dtm.Format("2006/01/02")
+			},
+			"sortDateTime": func(dtm time.Time) string {
+				return dtm.Format("2006/01/02 15:04:05")
+			},
+			"longDate": func(dtm time.Time) string {
+				return dtm.Format("Monday January 2, 2006 (MST)")
+			},
+			"longDateTime": func(dtm time.Time) string {
+				return dtm.Format("Monday January 2, 2006 at 15:04 (MST)")
+			},
+			"TableComment": func(tbl model.Table) string {
+				tableType := "table"
+				if tbl.TableType != "BASE TABLE" {
+					tableType = strings.ToLower(tbl.TableType)
+				}
+				txt := fmt.Sprintf("// %s is for %s `%s`", tbl.CodeName, tableType, tbl.TableName)
+				if tbl.CodeName != tbl.DisplayName {
+					txt += fmt.Sprintf(" (\"%s\")", tbl.DisplayName)
+				}
+				txt += ".\n"
+				if !tbl.IsUpdatable {
+					txt += "// It's READ ONLY.\n"
+				}
+				if len(tbl.Comment) > 0 {
+					txt += fmt.Sprintf("// %s", strings.ReplaceAll(tbl.Comment, "\n", "\n// "))
+					if !strings.HasSuffix(tbl.Comment, ".") {
+						txt += "."
+					}
+					txt += "\n"
+				}
+				return txt
+			},
+			"ColumnComment": func(col model.Column) string {
+				txt := fmt.Sprintf("// %s is for column `%s`", col.CodeName, col.ColumnName)
+				if col.CodeName != col.DisplayName {
+					txt += fmt.Sprintf(" (\"%s\")", col.DisplayName)
+				}
+				txt += ".\n"
+				if col.IsPrimaryKey {
+					txt += "// It's a PRIMARY KEY.\n"
+				}
+				if col.CanFilter {
+					txt += "// It's filterable/sortable.\n"
+				}
+				if col.IsVersion {
+					txt += "// It's checked and updated automatically for optimistic concurrency.\n"
+				}
+				if col.IsCreatedAt {
+					txt += "// It's set automatically on insert and never updated.\n"
+				}
+				if col.IsUpdatedAt {
+					txt += "// It's set automatically on insert and update.\n"
+				}
+				if col.IsSoftDelete {
+					txt += "// It's set when the row is soft-deleted (and those rows are hidden by default).\n"
+				}
+				if col.HasMaxLen {
+					txt += fmt.Sprintf("// It has a maximum size of %v.\n", *col.MaxLen)
+				}
+				if col.HasDefault {
+					txt += fmt.Sprintf("//\n// Default: %s\n", *col.ColumnDefault)
+				}
+				if len(col.Comment) > 0 {
+					txt += fmt.Sprintf("//\n// %s", strings.ReplaceAll(col.Comment, "\n", "\n// "))
+					if !strings.HasSuffix(col.Comment, ".") {
+						txt += "."
+					}
+					txt += "\n"
+				}
+				return txt
+			},
+			"CurrentFolder": os.Getwd,
+			"CommandLine": func() string {
+				return w.commandLine
+			},
+			"ConfigFile": func() string {
+				return w.configFile
+			},
+			"ConnectionStringEnvArg": func() string {
+				return w.connectionStringEnvArg
+			},
+			"SchemaName": func() string {
+				return w.schema.SchemaName
+			},
+			"SearchPath": func() string {
+				if len(w.schema.SchemaNames) == 0 {
+					return w.schema.SchemaName
+				}
+				return strings.Join(w.schema.SchemaNames, ",")
+			},
+			"ModuleName": func() string {
+				return w.module
+			},
+			"RepoName": func() string {
+				return w.repoName
+			},
+			"Notify": func() bool {
+				return w.notify
+			},
+			"PostgresFuncType": func(goType string) string {
+				if len(goType) < 2 {
+					return goType
+				}
+				f := goType
+				if strings.HasPrefix(f, "*") {
+					f = strings.TrimPrefix(f, "*") + "Nullable"
+				}
+				f = strings.ToUpper(f[0:1]) + f[1:]
+				return f
+			},
+			"inc":                              func(value int) int { return value + 1 },
+			"toColumnNameListCSV":              mapping.ToColumnNameListCSV,
+			"toColumnNameListNoPrimaryKeysCSV": mapping.ToColumnNameListNoPrimaryKeysCSV,
+			"toParameterListNoPrimaryKeysCSV":  mapping.ToParameterListNoPrimaryKeysCSV,
+			"toPrimaryKeyParametersCSV":        mapping.ToPrimaryKeyParametersCSV,
+			"toPrimaryKeyArgumentsCSV":         mapping.ToPrimaryKeyArgumentsCSV,
+			"toUpdateListNoPrimaryKeysCSV":     mapping.ToUpdateListNoPrimaryKeysCSV,
+			"columnIdxAfterPrimaryKeys":        mapping.ColumnIdxAfterPrimaryKeys,
+			"toAutomaticUpdates":               mapping.ToAutomaticUpdates,
+			"toCodeNameListCSV":                mapping.ToCodeNameListCSV,
+		}).ParseFS(tfs, "*.tmpl")
+		if err != nil {
+			return nil, err
+		}
+		if len(w.templatesFolder) > 0 {
+			if err := w.loadTemplateOverrides(t, w.templatesFolder); err != nil {
+				return nil, err
+			}
+		}
+		w.cache = t
+	}
+	return w.cache, nil
+}
+
+// loadTemplateOverrides parses the `*.tmpl` files in the folder. A file with
+// the same name as an embedded one (eg `repos.tmpl`) replaces it, either by
+// redefining it with `{{ define "repos" }}` or by being the whole template.
+// Other files add new templates, named after the file (eg `handlers.tmpl`).
+func (w *Writer) loadTemplateOverrides(t *template.Template, folder string) error {
+	filenames, err := filepath.Glob(filepath.Join(folder, "*.tmpl"))
+	if err != nil {
+		return err
+	}
+	for _, filename := range filenames {
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w.log, "Using template", filename)
+		base := filepath.Base(filename)
+		before := make(map[string]*parse.Tree)
+		for _, existing := range t.Templates() {
+			before[existing.Name()] = existing.Tree
+		}
+		body, err := t.New(base).Parse(string(data))
+		if err != nil {
+			return fmt.Errorf("%s: %w", filename, err)
+		}
+		if !isBlankTemplate(body) {
+			if _, err := t.AddParseTree(strings.TrimSuffix(base, ".tmpl"), body.Tree); err != nil {
+				return fmt.Errorf("%s: %w", filename, err)
+			}
+		}
+		// Remember which templates came from this file, for error messages.
+		for _, parsed := range t.Templates() {
+			if parsed.Tree != before[parsed.Name()] {
+				w.overrides[parsed.Name()] = filename
+			}
+		}
+	}
+	return nil
+}
+
+// templateFile returns the file the named template was parsed from, or an
+// empty string if it isn't known.
+func (w *Writer) templateFile(name string) string {
+	if filename, ok := w.overrides[name]; ok {
+		return filename
+	}
+	t, err := w.templates()
+	if err != nil {
+		return ""
+	}
+	if named := t.Lookup(name); named != nil && named.Tree != nil {
+		return named.Tree.ParseName
+	}
+	return ""
+}
+
+// isBlankTemplate returns true if the template has no content outside of any
+// templates it defines (which is the case for the embedded ones).
+func isBlankTemplate(t *template.Template) bool {
+	if t == nil || t.Tree == nil || t.Tree.Root == nil {
+		return true
+	}
+	for _, node := range t.Tree.Root.Nodes {
+		text, ok := node.(*parse.TextNode)
+		if !ok || len(bytes.TrimSpace(text.Text)) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// renderPath returns an output path pattern (eg `handlers/{{ .SlugName }}.go`)
+// rendered for the data, with the same functions as the templates.
+func (w *Writer) renderPath(pattern string, data interface{}) (string, error) {
+	templates, err := w.templates()
+	if err != nil {
+		return "", err
+	}
+	t, err := templates.New("path:" + pattern).Parse(pattern)
+	if err != nil {
+		return "", err
+	}
+	var wr bytes.Buffer
+	if err := t.Execute(&wr, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(wr.String()), nil
+}
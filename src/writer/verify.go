@@ -0,0 +1,113 @@
+package writer
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/kcartlidge/ng/src/model"
+	"golang.org/x/tools/go/packages"
+)
+
+// Verify type-checks the written packages as part of the parent module
+// in the output folder, which needs the Go toolchain. Errors are displayed
+// against the table/column which produced them. Returns true if there are any.
+func (w *Writer) Verify() (bool, error) {
+	fmt.Fprintln(w.log, "Verifying the generated code compiles")
+	fmt.Fprintln(w.log)
+	cfg := &packages.Config{
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedCompiledGoFiles |
+			packages.NeedImports | packages.NeedDeps | packages.NeedTypes | packages.NeedSyntax | packages.NeedTypesInfo,
+		Dir: w.topFolder,
+	}
+	pkgs, err := packages.Load(cfg, "./"+w.repoName+"/...")
+	if err != nil {
+		return true, fmt.Errorf("unable to load the generated packages: %w", err)
+	}
+
+	count := 0
+	for _, pkg := range pkgs {
+		for _, e := range pkg.Errors {
+			count++
+			w.showVerifyError(e)
+		}
+	}
+	if count == 0 {
+		fmt.Fprintln(w.log, "  Generated code compiles")
+	} else {
+		fmt.Fprintln(w.log)
+		fmt.Fprintf(w.log, "  %d error(s) found\n", count)
+	}
+	return count > 0, nil
+}
+
+// showVerifyError displays a type-checking error, along with the template and
+// table/column which produced the offending line (if known).
+func (w *Writer) showVerifyError(e packages.Error) {
+	filename, line := splitErrorPos(e.Pos)
+	f, found := w.findFile(filename)
+	if !found && len(e.Pos) > 0 {
+		fmt.Fprintf(w.log, "  %s: %s\n", e.Pos, e.Msg)
+		return
+	}
+	if !found {
+		fmt.Fprintf(w.log, "  %s\n", e.Msg)
+		return
+	}
+	fmt.Fprintf(w.log, "  %s:%d: %s\n", w.relativeName(f.filename), line, e.Msg)
+	lines := splitLines(f.content)
+	if line < 1 || line > len(lines) {
+		return
+	}
+	source := lines[line-1]
+	fmt.Fprintf(w.log, "    %d | %s\n", line, strings.TrimSpace(source))
+	about := fmt.Sprintf("from the `%s` template", f.template)
+	if f.table != nil {
+		about += fmt.Sprintf(", for table `%s.%s`", f.table.SchemaName, f.table.TableName)
+		if col, ok := findColumn(*f.table, source, e.Msg); ok {
+			about += fmt.Sprintf(", column `%s` (%s)", col.ColumnName, col.SqlType)
+		}
+	}
+	fmt.Fprintf(w.log, "    %s\n", about)
+}
+
+// findFile returns the generated file with the given (absolute) filename.
+func (w *Writer) findFile(filename string) (generatedFile, bool) {
+	for _, f := range w.files {
+		if abs, err := filepath.Abs(f.filename); err == nil && abs == filename {
+			return f, true
+		}
+	}
+	return generatedFile{}, false
+}
+
+// findColumn returns the column most likely to be involved in the error, being
+// the longest code name mentioned (eg `SetEmail`) by the source line or message.
+func findColumn(table model.Table, source string, msg string) (model.Column, bool) {
+	var result model.Column
+	found := false
+	for _, col := range table.Columns {
+		re := regexp.MustCompile(regexp.QuoteMeta(col.CodeName) + `\b`)
+		if !re.MatchString(source) && !re.MatchString(msg) {
+			continue
+		}
+		if !found || len(col.CodeName) > len(result.CodeName) {
+			result, found = col, true
+		}
+	}
+	return result, found
+}
+
+var errorPos = regexp.MustCompile(`^(.*?):(\d+)(?::\d+)?$`)
+
+// splitErrorPos splits a `file:line:col` (or `file:line`) position.
+func splitErrorPos(pos string) (string, int) {
+	m := errorPos.FindStringSubmatch(pos)
+	if m == nil {
+		return pos, 0
+	}
+	line, _ := strconv.Atoi(m[2])
+	return m[1], line
+}
@@ -0,0 +1,398 @@
+// Package writer generates Go repository code for a schema model, using the
+// (embedded or overridden) templates listed in the outputs manifest.
+package writer
+
+import (
+	"errors"
+	"fmt"
+	goscanner "go/scanner"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"text/template"
+
+	"github.com/kcartlidge/ng/src/mapping"
+	"github.com/kcartlidge/ng/src/model"
+	"golang.org/x/tools/imports"
+)
+
+// generatedFile is the (formatted) content rendered for a file.
+type generatedFile struct {
+	filename string
+	template string
+	table    *model.Table
+	content  []byte
+	isGo     bool
+}
+
+// Options control where and how the code is generated.
+// Progress is written to the log, if given.
+type Options struct {
+	// Folder is the parent module's folder, and RepoName the sub-folder
+	// (and package) within it for the generated code.
+	Folder   string
+	RepoName string
+
+	// Module is the Go module path for the generated code (eg `kcartlidge/app/data`).
+	Module string
+
+	// CommandLine and ConfigFile are recorded in the generated README.
+	CommandLine string
+	ConfigFile  string
+
+	ConnectionStringEnvArg string
+	Notify                 bool
+
+	// TemplatesFolder overrides (or adds to) the embedded templates.
+	// Outputs are added to those in the outputs manifest (see `LoadOutputs`).
+	TemplatesFolder string
+	Outputs         []Output
+
+	// Namer provides the `plural` template function, using the default rules
+	// if there isn't one. It should be the one the schema was scanned with.
+	Namer *mapping.Namer
+
+	Log io.Writer
+}
+
+// Writer renders the templates for a schema, and writes (or checks) the results.
+type Writer struct {
+	topFolder, repoFolder                     string
+	schema                                    model.Schema
+	commandLine, configFile, module, repoName string
+	connectionStringEnvArg                    string
+	notify                                    bool
+	templatesFolder                           string
+	outputs                                   []Output
+	files                                     []generatedFile
+	removed                                   int
+	cache                                     *template.Template
+	overrides                                 map[string]string
+	namer                                     *mapping.Namer
+	log                                       io.Writer
+}
+
+// New creates a writer for the schema.
+func New(schema model.Schema, options Options) (*Writer, error) {
+	if len(options.Folder) == 0 || len(options.Module) == 0 || len(options.RepoName) == 0 {
+		return nil, errors.New("the folder, module, and repo are all required")
+	}
+	outputs, err := LoadOutputs(options.TemplatesFolder, options.Outputs)
+	if err != nil {
+		return nil, err
+	}
+	if options.Namer == nil {
+		options.Namer = mapping.NewNamer(nil, nil, nil)
+	}
+	if options.Log == nil {
+		options.Log = io.Discard
+	}
+	w := &Writer{
+		topFolder:              path.Clean(options.Folder),
+		repoFolder:             path.Join(options.Folder, options.RepoName),
+		module:                 options.Module,
+		commandLine:            options.CommandLine,
+		configFile:             options.ConfigFile,
+		connectionStringEnvArg: options.ConnectionStringEnvArg,
+		schema:                 schema,
+		repoName:               options.RepoName,
+		notify:                 options.Notify,
+		templatesFolder:        options.TemplatesFolder,
+		outputs:                outputs,
+		files:                  []generatedFile{},
+		overrides:              make(map[string]string),
+		namer:                  options.Namer,
+		log:                    options.Log,
+	}
+	return w, nil
+}
+
+// Write generates the code and writes it to the output folder.
+// Only new or changed files are written, so unchanged ones keep their
+// modification times. Previously generated files which are no longer
+// needed are removed, but any other files in the folder are left alone.
+func (w *Writer) Write() error {
+	if err := w.render(); err != nil {
+		return err
+	}
+	if err := w.createOutputFolders(); err != nil {
+		return err
+	}
+	if err := w.createEditorConfigIfNotExists(); err != nil {
+		return err
+	}
+	if err := w.removeStaleFiles(); err != nil {
+		return err
+	}
+	return w.saveFiles()
+}
+
+// DryRun generates the code in memory and shows what writing it would
+// create, change (with unified diffs), or remove. Nothing is written.
+func (w *Writer) DryRun() error {
+	if err := w.render(); err != nil {
+		return err
+	}
+	fmt.Fprintln(w.log, "Dry run against", w.repoFolder)
+	fmt.Fprintln(w.log)
+	changes, err := w.planChanges(false)
+	if err != nil {
+		return err
+	}
+	if w.showChanges(changes, true) == 0 {
+		fmt.Fprintln(w.log, "  No changes")
+	}
+	return nil
+}
+
+// Check generates the code in memory and compares it with the output folder,
+// without writing anything. It displays a summary and returns true if anything differs.
+func (w *Writer) Check() (bool, error) {
+	if err := w.render(); err != nil {
+		return false, err
+	}
+	return w.compareFiles()
+}
+
+// render generates (and formats) all the output files in memory.
+func (w *Writer) render() error {
+	w.files = []generatedFile{}
+	if err := w.checkPrimaryKeys(); err != nil {
+		return err
+	}
+	if err := w.createDumpFile(); err != nil {
+		return err
+	}
+	if err := w.createOutputs(); err != nil {
+		return err
+	}
+
+	if err := w.applyFormatting(); err != nil {
+		return err
+	}
+	return w.createManifest()
+}
+
+// saveFiles writes any new or changed files to disk, and reports the counts.
+func (w *Writer) saveFiles() error {
+	fmt.Fprintln(w.log, "Writing files")
+	changes, err := w.planChanges(true)
+	if err != nil {
+		return err
+	}
+	counts := make(map[int]int)
+	for _, c := range changes {
+		counts[c.kind]++
+		switch c.kind {
+		case fileCreated, fileChanged:
+			if err := os.MkdirAll(path.Dir(c.filename), 0755); err != nil {
+				return err
+			}
+			if err := os.WriteFile(c.filename, c.content, fs.ModePerm); err != nil {
+				return err
+			}
+		}
+	}
+	fmt.Fprintf(w.log, "Files: %d unchanged, %d updated, %d added, %d removed\n",
+		counts[fileUnchanged], counts[fileChanged], counts[fileCreated], w.removed)
+	return nil
+}
+
+// removeStaleFiles removes previously generated files (per the manifest) which
+// are no longer generated, such as those for dropped tables, along with any
+// folders left empty. Other files, such as ones added by hand, are kept.
+// The output folder itself is never removed as depending upon the OS that
+// can leave existing terminal/command sessions in that folder seeming okay
+// but actually working against the original folder in the trash (which
+// can go unnoticed).
+func (w *Writer) removeStaleFiles() error {
+	_, found, err := w.previousFiles()
+	if err != nil {
+		return err
+	}
+	if !found {
+		fmt.Fprintln(w.log, "No previous manifest, so no files will be removed")
+		return nil
+	}
+	changes, err := w.planChanges(true)
+	if err != nil {
+		return err
+	}
+	for _, c := range changes {
+		if c.kind != fileRemoved {
+			continue
+		}
+		fmt.Fprintln(w.log, "Removing", c.name)
+		if err := os.Remove(c.filename); err != nil {
+			return err
+		}
+		w.removed++
+		for folder := path.Dir(c.filename); strings.HasPrefix(folder, w.repoFolder+"/"); folder = path.Dir(folder) {
+			entries, err := os.ReadDir(folder)
+			if err != nil || len(entries) > 0 {
+				break
+			}
+			if err := os.Remove(folder); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (w *Writer) createOutputFolders() error {
+	fmt.Fprintln(w.log, "Ensuring target folder/sub-folders exist")
+	if err := os.MkdirAll(w.topFolder, 0755); err != nil {
+		return err
+	}
+	return os.MkdirAll(w.repoFolder, 0755)
+}
+
+func (w *Writer) createEditorConfigIfNotExists() error {
+	filename := path.Join(w.topFolder, ".editorconfig")
+	if _, err := os.Stat(filename); !os.IsNotExist(err) {
+		return nil
+	}
+	fmt.Fprintln(w.log, "Adding missing editorconfig")
+	b, err := w.getTemplatedData(nil, "editorconfig")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, b, fs.ModePerm)
+}
+
+func (w *Writer) createDumpFile() error {
+	fmt.Fprintln(w.log, "Creating JSON dump file")
+	filename := path.Join(w.repoFolder, "dump.json")
+	b, err := w.schema.ToJSON()
+	if err != nil {
+		return err
+	}
+	w.files = append(w.files, generatedFile{filename: filename, content: b})
+	return nil
+}
+
+// checkPrimaryKeys ensures updatable tables have a primary key.
+func (w *Writer) checkPrimaryKeys() error {
+	for _, table := range w.schema.Tables {
+		hasPrimary := false
+		for _, c := range table.Columns {
+			if c.IsPrimaryKey {
+				hasPrimary = true
+			}
+		}
+		if table.IsUpdatable && !hasPrimary {
+			return fmt.Errorf("%s.%s has no primary key", table.SchemaName, table.TableName)
+		}
+	}
+	return nil
+}
+
+// createOutputs renders the templates listed in the outputs manifest,
+// either once for the schema or once per table.
+func (w *Writer) createOutputs() error {
+	for _, o := range w.outputs {
+		if o.Scope == ScopeSchema {
+			if err := w.createOutput(o, w.schema); err != nil {
+				return err
+			}
+			continue
+		}
+		fmt.Fprintf(w.log, "Creating %s for each table\n", o.Template)
+		for _, table := range w.schema.Tables {
+			if err := w.createOutput(o, table); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// createOutput renders an output's template to its path (within the repo folder).
+func (w *Writer) createOutput(o Output, data interface{}) error {
+	name, err := w.renderPath(o.Path, data)
+	if err != nil {
+		return fmt.Errorf("output path %s: %w", o.Path, err)
+	}
+	filename := path.Join(w.repoFolder, name)
+	if len(name) == 0 || path.IsAbs(name) || !strings.HasPrefix(filename, w.repoFolder+"/") {
+		return fmt.Errorf("output path %s gives %q, which is not within the repo folder", o.Path, name)
+	}
+	for _, f := range w.files {
+		if f.filename == filename {
+			return fmt.Errorf("output path %s gives %s, which is already generated", o.Path, name)
+		}
+	}
+	if o.Scope == ScopeSchema {
+		fmt.Fprintln(w.log, "Creating", name)
+	}
+	if strings.HasSuffix(filename, ".go") {
+		return w.writeGoFile(filename, o.Template, data)
+	}
+	return w.writeFile(filename, o.Template, data)
+}
+
+// applyFormatting formats the generated Go source in-process, also removing
+// any unused imports. Syntax errors are reported against the template.
+func (w *Writer) applyFormatting() error {
+	fmt.Fprintln(w.log, "Formatting generated Go source")
+	options := &imports.Options{Comments: true, TabIndent: true, TabWidth: 8}
+	for i, f := range w.files {
+		if f.isGo {
+			b, err := imports.Process(f.filename, f.content, options)
+			if err != nil {
+				return w.syntaxError(f, err)
+			}
+			w.files[i].content = b
+		}
+	}
+	return nil
+}
+
+// syntaxError describes invalid generated Go source in terms of the
+// template which produced it, including the offending line. Line numbers
+// are in the generated output, as the template source lines aren't known.
+func (w *Writer) syntaxError(f generatedFile, err error) error {
+	source := fmt.Sprintf("rendered by the `%s` template", f.template)
+	if file := w.templateFile(f.template); len(file) > 0 {
+		source += " in " + file
+	}
+	var list goscanner.ErrorList
+	if !errors.As(err, &list) || len(list) == 0 {
+		return fmt.Errorf("%s (%s): %w", w.relativeName(f.filename), source, err)
+	}
+	first := list[0]
+	msg := fmt.Sprintf("%s (%s) has invalid Go at line %d of the generated output: %s",
+		w.relativeName(f.filename), source, first.Pos.Line, first.Msg)
+	if lines := splitLines(f.content); first.Pos.Line > 0 && first.Pos.Line <= len(lines) {
+		msg += fmt.Sprintf("\n  %d | %s", first.Pos.Line, lines[first.Pos.Line-1])
+	}
+	if len(list) > 1 {
+		msg += fmt.Sprintf("\n  (and %d more)", len(list)-1)
+	}
+	return errors.New(msg)
+}
+
+func (w *Writer) writeGoFile(filename string, templateName string, data interface{}) error {
+	b, err := w.getTemplatedData(data, templateName)
+	if err != nil {
+		return err
+	}
+	f := generatedFile{filename: filename, template: templateName, content: b, isGo: true}
+	if table, ok := data.(model.Table); ok {
+		f.table = &table
+	}
+	w.files = append(w.files, f)
+	return nil
+}
+
+func (w *Writer) writeFile(filename string, templateName string, data interface{}) error {
+	b, err := w.getTemplatedData(data, templateName)
+	if err != nil {
+		return err
+	}
+	w.files = append(w.files, generatedFile{filename: filename, template: templateName, content: b})
+	return nil
+}